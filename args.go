@@ -6,50 +6,89 @@ import (
 
 // ArgsLooker looks up keys in []string, like the one in os.Args.
 type ArgsLooker struct {
+	// DefaultValue is the value of args without "=" (e.g, "-verbose"). NewArgs sets it to "1",
+	// which Lookup can save into bool, int, etc. but string fields get "1" as well. Set it (e.g, to
+	// "true" or "") before the first call to LookupKey or Keys.
+	DefaultValue string
+
 	args []string
 	rex  *regexp.Regexp
 
 	extraArgs []string
 	data      Map
+	// Valid args in order, their keys and the keys requested by LookupKey.
+	validArgs []string
+	argKeys   []string
+	used      map[string]bool
 }
 
 // NewArgs returns a Looker to access program arguments (e.g, os.Args).
 // Suggestions for prefix: "-", "--env-" or even "".
 // Valid args (<prefix><NAME>=<value>) are processed by LookupKey and the rest is available with ExtraArgs.
+// For "-K" without "=", the value is DefaultValue and for "-K=" it is empty. Bool fields get true
+// and false, respectively, int fields get 1 and an error, and string fields get "1" and "".
 func NewArgs(prefix string, args []string) *ArgsLooker {
 	l := ArgsLooker{
-		rex:  regexp.MustCompile(`^` + regexp.QuoteMeta(prefix) + `([^=]*)(?:(=)(.*))?$`),
-		args: make([]string, len(args)),
+		DefaultValue: "1",
+		rex:          regexp.MustCompile(`^` + regexp.QuoteMeta(prefix) + `([^=]*)(?:(=)(.*))?$`),
+		args:         make([]string, len(args)),
 	}
 	copy(l.args, args)
 	return &l
 }
 
 // ExtraArgs returns args that are not formatted for ArgsLooker. Generally your program should process them.
-// It is empty before the first call to LookupKey.
+// It is empty before the first call to LookupKey or Keys.
 func (l *ArgsLooker) ExtraArgs() []string {
 	return l.extraArgs
 }
 
 // LookupKey processes provided args (1st call only) and looks up the value of k.
 func (l *ArgsLooker) LookupKey(k string) (string, bool, error) {
-	if l.data == nil {
-		l.data = make(Map)
-
-		for _, arg := range l.args {
-			res := l.rex.FindStringSubmatch(arg)
-			if len(res) < 4 {
-				l.extraArgs = append(l.extraArgs, arg)
-				continue
-			}
-
-			val := res[3]
-			if res[2] == "" {
-				// default for syntax "-KEY" is 1, which Lookup can save into an int, bool, etc.
-				val = "1"
-			}
-			l.data[res[1]] = val
+	l.parse()
+	l.used[k] = true
+	return l.data.LookupKey(k)
+}
+
+// UnusedArgs returns valid args whose keys were never looked up, e.g. misspelled flags after Lookup.
+// Unlike ExtraArgs, they have the prefix.
+func (l *ArgsLooker) UnusedArgs() []string {
+	l.parse()
+	var unused []string
+	for i, k := range l.argKeys {
+		if !l.used[k] {
+			unused = append(unused, l.validArgs[i])
 		}
 	}
-	return l.data.LookupKey(k)
+	return unused
+}
+
+// Keys processes provided args (1st call only) and returns all keys found in them.
+func (l *ArgsLooker) Keys() []string {
+	l.parse()
+	return l.data.Keys()
+}
+
+func (l *ArgsLooker) parse() {
+	if l.data != nil {
+		return
+	}
+	l.data = make(Map)
+	l.used = make(map[string]bool)
+
+	for _, arg := range l.args {
+		res := l.rex.FindStringSubmatch(arg)
+		if len(res) < 4 {
+			l.extraArgs = append(l.extraArgs, arg)
+			continue
+		}
+		l.validArgs = append(l.validArgs, arg)
+		l.argKeys = append(l.argKeys, res[1])
+
+		val := res[3]
+		if res[2] == "" {
+			val = l.DefaultValue
+		}
+		l.data[res[1]] = val
+	}
 }
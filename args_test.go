@@ -69,3 +69,72 @@ other//false/<nil>
 		t.Errorf("Unexpected extra args: got %q instead of []", extra)
 	}
 }
+
+func TestArgsLookerDefaultValue(t *testing.T) {
+	for _, def := range []string{"true", ""} {
+		t.Run(def, func(t *testing.T) {
+			l := lookup.NewArgs("-", []string{"-verbose", "-name=x"})
+			l.DefaultValue = def
+			v, got, err := l.LookupKey("verbose")
+			if v != def || !got || err != nil {
+				t.Errorf("Unexpected result: got %q/%t/%v, expecting %q", v, got, err, def)
+			}
+		})
+	}
+}
+
+func TestArgsLookerUnusedArgs(t *testing.T) {
+	l := lookup.NewArgs("--", []string{"--port=80", "--typpo=1", "file.txt", "--verbose"})
+	var c struct {
+		Port    int  `lookup:"port"`
+		Verbose bool `lookup:"verbose,optional"`
+	}
+	if err := lookup.Lookup(&c, nil, l); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	unused := fmt.Sprint(l.UnusedArgs())
+	if expected := fmt.Sprint([]string{"--typpo=1"}); unused != expected {
+		t.Errorf("Unexpected unused args: got %q, expecting %q", unused, expected)
+	}
+}
+
+func TestArgsLookerGNUStyle(t *testing.T) {
+	tests := []struct {
+		arg  string
+		b    bool
+		n    int
+		nErr bool
+		s    string
+	}{
+		{"--K", true, 1, false, "1"},
+		{"--K=", false, 0, true, ""},
+		{"--K=true", true, 0, true, "true"},
+		{"--K=false", false, 0, true, "false"},
+	}
+	for _, test := range tests {
+		t.Run(test.arg, func(t *testing.T) {
+			var b struct {
+				K bool `lookup:"K"`
+			}
+			b.K = !test.b
+			if err := lookup.Lookup(&b, nil, lookup.NewArgs("--", []string{test.arg})); err != nil || b.K != test.b {
+				t.Errorf("Unexpected bool result: got %t/%v, expecting %t", b.K, err, test.b)
+			}
+
+			var n struct {
+				K int `lookup:"K"`
+			}
+			err := lookup.Lookup(&n, nil, lookup.NewArgs("--", []string{test.arg}))
+			if (err != nil) != test.nErr || n.K != test.n {
+				t.Errorf("Unexpected int result: got %d/%v, expecting %d", n.K, err, test.n)
+			}
+
+			var s struct {
+				K string `lookup:"K"`
+			}
+			if err := lookup.Lookup(&s, nil, lookup.NewArgs("--", []string{test.arg})); err != nil || s.K != test.s {
+				t.Errorf("Unexpected string result: got %q/%v, expecting %q", s.K, err, test.s)
+			}
+		})
+	}
+}
@@ -0,0 +1,96 @@
+package lookup
+
+import "sync"
+
+// NamedLooker pairs a Looker with a name for NewChain (e.g, "env" or a file name).
+type NamedLooker struct {
+	Name string
+	Looker
+}
+
+// Keys returns the keys of the embedded Looker, or nil if it is not Enumerable.
+func (l NamedLooker) Keys() []string {
+	keys, _ := keysOf(l.Looker)
+	return keys
+}
+
+// KeysErr is like Keys, but also returns the error loading the keys of the embedded Looker.
+func (l NamedLooker) KeysErr() ([]string, error) {
+	return keysOf(l.Looker)
+}
+
+// Chain is a Looker that tries its sources in order and records which one provided each key.
+type Chain struct {
+	sources []NamedLooker
+
+	mutex   sync.Mutex
+	winners map[string]string
+}
+
+// NewChain returns a Chain trying sources in order, like passing them to Lookup. Items wrapped with
+// OnError keep their policy, otherwise errors are returned and subject to Options.ErrorPolicy as a
+// whole.
+func NewChain(sources ...NamedLooker) *Chain {
+	return &Chain{
+		sources: append([]NamedLooker(nil), sources...),
+		winners: make(map[string]string),
+	}
+}
+
+// LookupKey tries the sources in order until k is found.
+func (c *Chain) LookupKey(k string) (string, bool, error) {
+	for _, s := range c.sources {
+		v, ok, err := s.LookupKey(k)
+		if pl, isPolicy := s.Looker.(*policyLooker); isPolicy && err != nil && pl.policy == SkipOnError {
+			continue
+		}
+		if err != nil {
+			return "", false, err
+		}
+		if ok {
+			c.mutex.Lock()
+			c.winners[k] = s.Name
+			c.mutex.Unlock()
+			return v, true, nil
+		}
+	}
+	return "", false, nil
+}
+
+// Keys returns the union of keys provided by Enumerable sources, sorted.
+func (c *Chain) Keys() []string {
+	l := make([]Looker, len(c.sources))
+	for i, s := range c.sources {
+		l[i] = s.Looker
+	}
+	return ListAll(l...)
+}
+
+// KeysErr is like Keys, but returns the first error of a source that fails to load its keys,
+// unless it is wrapped with OnError and SkipOnError.
+func (c *Chain) KeysErr() ([]string, error) {
+	l := make([]Looker, len(c.sources))
+	for i, s := range c.sources {
+		l[i] = s.Looker
+	}
+	return listAll(l, FailFast, discard)
+}
+
+// Source returns the name of the source that provided k the last time it was found.
+func (c *Chain) Source(k string) (string, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	name, ok := c.winners[k]
+	return name, ok
+}
+
+// Sources returns a copy of the names of the sources that provided each key found so far.
+func (c *Chain) Sources() map[string]string {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	m := make(map[string]string, len(c.winners))
+	for k, v := range c.winners {
+		m[k] = v
+	}
+	return m
+}
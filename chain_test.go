@@ -0,0 +1,93 @@
+package lookup_test
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/carloslenz/lookup"
+)
+
+func TestChain(t *testing.T) {
+	var c struct {
+		Host  string `lookup:"HOST"`
+		Port  int    `lookup:"PORT"`
+		Debug bool   `lookup:"DEBUG,optional"`
+	}
+	failing := lookup.LookerFunc(func(string) (string, bool, error) {
+		return "", false, errors.New("unavailable")
+	})
+	chain := lookup.NewChain(
+		lookup.NamedLooker{Name: "remote", Looker: lookup.OnError(failing, lookup.SkipOnError)},
+		lookup.NamedLooker{Name: "env", Looker: lookup.Map{"HOST": "example.com"}},
+		lookup.NamedLooker{Name: "defaults", Looker: lookup.Map{"HOST": "localhost", "PORT": "80"}},
+	)
+	if err := lookup.Lookup(&c, nil, chain); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if c.Host != "example.com" || c.Port != 80 {
+		t.Errorf("Unexpected result: %#v", c)
+	}
+	expected := map[string]string{"HOST": "env", "PORT": "defaults"}
+	if sources := chain.Sources(); !reflect.DeepEqual(sources, expected) {
+		t.Errorf("Unexpected sources: %v, expecting %v", sources, expected)
+	}
+	if name, ok := chain.Source("DEBUG"); ok {
+		t.Errorf("DEBUG was not found, yet source = %q", name)
+	}
+	if keys := chain.Keys(); !reflect.DeepEqual(keys, []string{"HOST", "PORT"}) {
+		t.Errorf("Unexpected keys: %v", keys)
+	}
+
+	s := make(sources)
+	if err := lookup.Lookup(&c, s, chain); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if expected := (sources{"HOST": "env", "PORT": "defaults"}); !reflect.DeepEqual(s, expected) {
+		t.Errorf("Unexpected reported sources: %v, expecting %v", s, expected)
+	}
+
+	named := lookup.NamedLooker{Name: "m", Looker: lookup.Map{"HOST": "h", "PORT": "1", "TYPO": "x"}}
+	if err := (lookup.Options{Strict: true}).Lookup(&c, nil, named); err == nil {
+		t.Error("TYPO is unknown, why no error?!")
+	}
+
+	failFast := lookup.NewChain(lookup.NamedLooker{Name: "remote", Looker: failing})
+	if err := lookup.Lookup(&c, nil, failFast); err == nil {
+		t.Error("Source fails, why no error?!")
+	}
+}
+
+func TestLookupSourceTag(t *testing.T) {
+	type conf struct {
+		Host string `lookup:"HOST"`
+		Pass string `lookup:"DB_PASS,source=vault"`
+	}
+	env := lookup.NamedLooker{Name: "env", Looker: lookup.Map{"HOST": "localhost", "DB_PASS": "from env"}}
+	vault := lookup.NamedLooker{Name: "vault", Looker: lookup.Map{"HOST": "vault", "DB_PASS": "secret"}}
+
+	var c conf
+	if err := lookup.Lookup(&c, nil, env, vault); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if c.Host != "localhost" || c.Pass != "secret" {
+		t.Errorf("Unexpected result: %#v", c)
+	}
+
+	c = conf{}
+	if err := lookup.Lookup(&c, nil, lookup.NewChain(env, vault)); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if c.Host != "localhost" || c.Pass != "secret" {
+		t.Errorf("Unexpected result: %#v", c)
+	}
+
+	err := lookup.Lookup(&c, nil, env)
+	if err == nil || !strings.Contains(err.Error(), `source "vault" is not in seq`) {
+		t.Errorf("Unexpected error for missing source: %v", err)
+	}
+	if err := lookup.Lookup(&c, nil, env, lookup.NamedLooker{Name: "vault", Looker: lookup.Map{}}); err == nil {
+		t.Error("DB_PASS is missing from vault, why no error?!")
+	}
+}
@@ -0,0 +1,138 @@
+package lookup
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+)
+
+var defaultRef = regexp.MustCompile(`\$\{([^}]*)\}`)
+
+// deferredDefault is a field whose default references keys that were not resolved yet.
+type deferredDefault struct {
+	field     reflect.Value
+	fieldType reflect.StructField
+	tag       fieldTag
+	prefix    string
+	path      string
+	key       string
+}
+
+// setDefault sets the field of d with its default. Defaults referencing keys without values are
+// deferred, unless final.
+func (s *lookupState) setDefault(d deferredDefault, final bool) error {
+	var unresolved []string
+	v := defaultRef.ReplaceAllStringFunc(d.tag.def, func(ref string) string {
+		k := ref[2 : len(ref)-1]
+		r, ok := s.resolved[k]
+		if !ok {
+			unresolved = append(unresolved, k)
+		}
+		return r
+	})
+	switch {
+	case len(unresolved) > 0 && final:
+		return fmt.Errorf("default of field %q references keys without values (or in a cycle): %q",
+			d.fieldType.Name, unresolved)
+	case len(unresolved) > 0:
+		s.deferred = append(s.deferred, d)
+		return nil
+	}
+	s.resolved[d.prefix+d.tag.key] = v
+	if s.groups > 0 {
+		// Forgotten if the group is dropped (see lookupNested).
+		s.groupDefaults = append(s.groupDefaults, d.prefix+d.tag.key)
+		s.reports.defaults = true
+		defer func() { s.reports.defaults = false }()
+	}
+	if err := s.setFound(d.field, d.fieldType, d.tag, d.prefix, d.path, d.key, v); err != nil {
+		return err
+	}
+	reportSource(s.r, d.key, DefaultSource)
+	return nil
+}
+
+// expandDeferred sets deferred defaults as their references get values, until none is left.
+func (s *lookupState) expandDeferred() error {
+	for len(s.deferred) > 0 {
+		pending := s.deferred
+		s.deferred = nil
+		for _, d := range pending {
+			if err := s.fail(s.setDefault(d, false)); err != nil {
+				return err
+			}
+		}
+		if len(s.deferred) == len(pending) {
+			// No progress: references can't be resolved anymore.
+			for _, d := range s.deferred {
+				if err := s.fail(s.setDefault(d, true)); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+	}
+	return nil
+}
+
+// reportBuffer records the calls to Reporter in groups, so they are replayed after the outermost
+// one without those made by defaults of dropped groups.
+type reportBuffer struct {
+	calls []reportCall
+	// Whether calls are being made by setDefault.
+	defaults bool
+}
+
+type reportCall struct {
+	f         func(r Reporter)
+	byDefault bool
+}
+
+func (b *reportBuffer) add(f func(r Reporter)) {
+	b.calls = append(b.calls, reportCall{f, b.defaults})
+}
+
+// dropDefaults removes the calls made by defaults since the first calls.
+func (b *reportBuffer) dropDefaults(first int) {
+	calls := b.calls[:first]
+	for _, c := range b.calls[first:] {
+		if !c.byDefault {
+			calls = append(calls, c)
+		}
+	}
+	b.calls = calls
+}
+
+func (b *reportBuffer) replay(r Reporter) {
+	for _, c := range b.calls {
+		c.f(r)
+	}
+}
+
+func (b *reportBuffer) Report(key string, e interface{}) {
+	b.add(func(r Reporter) { r.Report(key, e) })
+}
+
+func (b *reportBuffer) ReportMissing(key string) {
+	b.add(func(r Reporter) { reportMissing(r, key) })
+}
+
+func (b *reportBuffer) ReportDeprecated(key, message string) {
+	b.add(func(r Reporter) { reportDeprecated(r, key, message) })
+}
+
+func (b *reportBuffer) ReportAlias(key, alias string) {
+	b.add(func(r Reporter) { reportAlias(r, key, alias) })
+}
+
+func (b *reportBuffer) ReportSource(key, source string) {
+	b.add(func(r Reporter) { reportSource(r, key, source) })
+}
+
+func (b *reportBuffer) ReportShadowed(key, source, value, winner, winning string) {
+	b.add(func(r Reporter) { reportShadowed(r, key, source, value, winner, winning) })
+}
+
+func (b *reportBuffer) ReportError(key string, err error) {
+	b.add(func(r Reporter) { reportError(r, key, err) })
+}
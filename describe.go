@@ -0,0 +1,89 @@
+package lookup
+
+import (
+	"reflect"
+	"strings"
+)
+
+// FieldDescriptor describes a field that Lookup fills in, e.g. for help messages.
+type FieldDescriptor struct {
+	// Key is the key looked up, including prefixes of nested structs. Fields tagged with ",join="
+	// have several keys separated by ":".
+	Key string
+	// Field is the path to the field, like "DB.Host".
+	Field string
+	// Type is the Go type of the field.
+	Type string
+	// Required is false for optional fields, and for fields tagged with ",requiredif=" or
+	// ",default=".
+	Required bool
+	// RequiredIf is the key that makes the field required, if any.
+	RequiredIf string
+	// Deprecated is the deprecation message, or "(deprecated)" if the tag has none.
+	Deprecated string
+	// Default is the value tagged with ",default=", before expanding references. See HasDefault.
+	Default    string
+	HasDefault bool
+}
+
+// Describe returns the fields of prototype, a struct or pointer to struct, that Lookup fills in.
+func Describe(prototype interface{}) []FieldDescriptor {
+	return Options{}.Describe(prototype)
+}
+
+// Describe is like the Describe function, customized by o.
+func (o Options) Describe(prototype interface{}) []FieldDescriptor {
+	t := reflect.TypeOf(prototype)
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return nil
+	}
+	var l []FieldDescriptor
+	o.describeStruct(t, "", "", &l)
+	return l
+}
+
+func (o *Options) describeStruct(t reflect.Type, prefix, path string, l *[]FieldDescriptor) {
+	for i := 0; i < t.NumField(); i++ {
+		fieldType := t.Field(i)
+		tag := findTag(fieldType.Tag, fieldType.Name)
+
+		if tag.nests(fieldType.Type) {
+			if fieldType.PkgPath != "" && !fieldType.Anonymous {
+				continue
+			}
+			nested := fieldType.Type
+			if nested.Kind() == reflect.Ptr {
+				nested = nested.Elem()
+			}
+			o.describeStruct(nested, o.nestedPrefix(prefix, fieldType, tag), path+fieldType.Name+".", l)
+			continue
+		}
+
+		if tag = o.fieldNameKey(fieldType, tag); tag.key == notFound || unsupportedKind(fieldType.Type.Kind()) {
+			continue
+		}
+
+		d := FieldDescriptor{
+			Key:        prefix + tag.key,
+			Field:      path + fieldType.Name,
+			Type:       fieldType.Type.String(),
+			Required:   !tag.optional && tag.requiredIf == "" && !tag.hasDefault,
+			RequiredIf: tag.requiredIf,
+			Default:    tag.def,
+			HasDefault: tag.hasDefault,
+		}
+		if tag.joined {
+			d.Key = prefix + strings.Replace(tag.key, ":", ":"+prefix, -1)
+		}
+		if tag.deprecated {
+			d.Deprecated = tag.deprecation
+			if d.Deprecated == "" {
+				d.Deprecated = "(deprecated)"
+			}
+		}
+		*l = append(*l, d)
+	}
+}
@@ -0,0 +1,42 @@
+package lookup_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/carloslenz/lookup"
+)
+
+func TestDescribe(t *testing.T) {
+	type DB struct {
+		Host string `lookup:"HOST"`
+		Port int    `lookup:"PORT,optional"`
+	}
+	type conf struct {
+		Name     string `json:"NAME"`
+		TLS      bool   `lookup:"TLS,optional"`
+		Cert     string `lookup:"CERT,requiredif=TLS"`
+		Old      string `lookup:"OLD,optional,deprecated=use NAME"`
+		DB       *DB    `lookup:"DB"`
+		Skipped  string `json:"-"`
+		Timeout  string `lookup:"TIMEOUT,default=1m,30s"`
+		Untagged string
+	}
+	expected := []lookup.FieldDescriptor{
+		{Key: "NAME", Field: "Name", Type: "string", Required: true},
+		{Key: "TLS", Field: "TLS", Type: "bool"},
+		{Key: "CERT", Field: "Cert", Type: "string", RequiredIf: "TLS"},
+		{Key: "OLD", Field: "Old", Type: "string", Deprecated: "use NAME"},
+		{Key: "DB_HOST", Field: "DB.Host", Type: "string", Required: true},
+		{Key: "DB_PORT", Field: "DB.Port", Type: "int"},
+		{Key: "TIMEOUT", Field: "Timeout", Type: "string", Default: "1m,30s", HasDefault: true},
+	}
+	if l := lookup.Describe(&conf{}); !reflect.DeepEqual(l, expected) {
+		t.Errorf("Unexpected descriptors:\n%+v\nexpecting\n%+v", l, expected)
+	}
+
+	l := lookup.Options{UseFieldNames: true, FieldNameTransform: lookup.ToScreamingSnake}.Describe(conf{})
+	if last := l[len(l)-1]; last.Key != "UNTAGGED" || !last.Required {
+		t.Errorf("Unexpected descriptor for untagged field: %+v", last)
+	}
+}
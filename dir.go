@@ -0,0 +1,89 @@
+package lookup
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+type dirLooker struct {
+	root   string
+	rename func(string) string
+}
+
+// NewDir returns a Looker that reads each key from the file with the same name in root, like
+// Kubernetes ConfigMap and Secret volumes. Files are read on every lookup, so updates are visible.
+func NewDir(root string) Looker {
+	return NewDirMapped(root, nil)
+}
+
+// DockerSecretsDir is where Docker and Swarm mount secrets.
+const DockerSecretsDir = "/run/secrets"
+
+// NewDockerSecrets returns a Looker for Docker secrets, which are files in DockerSecretsDir named
+// after their keys. A trailing newline is removed from each secret.
+func NewDockerSecrets() Looker {
+	return NewDir(DockerSecretsDir)
+}
+
+// NewDirMapped is like NewDir but the file name is rename(key), for keys with characters that
+// are not valid in file names. A nil rename is the identity.
+func NewDirMapped(root string, rename func(key string) string) Looker {
+	return &dirLooker{
+		root:   root,
+		rename: rename,
+	}
+}
+
+func (l *dirLooker) LookupKey(k string) (string, bool, error) {
+	if l.rename != nil {
+		k = l.rename(k)
+	}
+	filename := filepath.Join(l.root, k)
+	b, err := ioutil.ReadFile(filename)
+	if os.IsNotExist(err) {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, &SourceError{Source: filename, Err: err}
+	}
+	return trimNewline(string(b)), true, nil
+}
+
+type trimNewlineLooker struct {
+	inner Looker
+}
+
+// TrimNewline returns a Looker that removes a single trailing "\n" or "\r\n" from the values of
+// inner, e.g. for secrets read by commands like `$(cat secret)`. NewDir and NewDockerSecrets
+// already do it. Keys are those of inner, if it is Enumerable. See also Options.TrimNewline.
+func TrimNewline(inner Looker) Looker {
+	return &trimNewlineLooker{
+		inner: inner,
+	}
+}
+
+func (l *trimNewlineLooker) LookupKey(k string) (string, bool, error) {
+	v, ok, err := l.inner.LookupKey(k)
+	return trimNewline(v), ok, err
+}
+
+// Keys returns the keys of inner, or nil if it is not Enumerable.
+func (l *trimNewlineLooker) Keys() []string {
+	keys, _ := keysOf(l.inner)
+	return keys
+}
+
+// KeysErr is like Keys, but also returns the error loading the keys of inner.
+func (l *trimNewlineLooker) KeysErr() ([]string, error) {
+	return keysOf(l.inner)
+}
+
+// trimNewline removes a single trailing "\n" or "\r\n", as left by editors and `echo`.
+func trimNewline(s string) string {
+	if strings.HasSuffix(s, "\n") {
+		s = strings.TrimSuffix(s[:len(s)-1], "\r")
+	}
+	return s
+}
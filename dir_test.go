@@ -0,0 +1,108 @@
+package lookup_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/carloslenz/lookup"
+)
+
+func TestDirMapped(t *testing.T) {
+	root, err := ioutil.TempDir("", "lookup")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	files := map[string]string{
+		"app-port": "8080\n",
+		"app-name": "server\r\n",
+		"app-host": "localhost",
+	}
+	for name, contents := range files {
+		if err := ioutil.WriteFile(filepath.Join(root, name), []byte(contents), 0666); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	l := lookup.NewDirMapped(root, func(k string) string {
+		return strings.Replace(k, ".", "-", -1)
+	})
+	tests := []struct {
+		key, val string
+		found    bool
+	}{
+		{"app.port", "8080", true},
+		{"app.name", "server", true},
+		{"app.host", "localhost", true},
+		{"app.other", "", false},
+	}
+	for _, test := range tests {
+		t.Run(test.key, func(t *testing.T) {
+			v, got, err := l.LookupKey(test.key)
+			if v != test.val {
+				t.Errorf("Unexpected value: got %q, expecting %q", v, test.val)
+			}
+
+			if got != test.found {
+				t.Errorf("Unexpected bool result: got %t, expecting %t", got, test.found)
+			}
+
+			if err != nil {
+				t.Errorf("Unexpected error: got %q instead of nil", err)
+			}
+		})
+	}
+
+	if _, _, err := lookup.NewDir(root).LookupKey("app-port"); err != nil {
+		t.Errorf("Unexpected error: got %q instead of nil", err)
+	}
+}
+
+func TestDockerSecretsMissing(t *testing.T) {
+	v, ok, err := lookup.NewDockerSecrets().LookupKey("lookup-test-missing-secret")
+	if v != "" || ok || err != nil {
+		t.Errorf("Unexpected result for missing secret: %q/%t/%v", v, ok, err)
+	}
+}
+
+func TestTrimNewline(t *testing.T) {
+	type conf struct {
+		Key  []byte `lookup:"KEY,enc=hex"`
+		Name string `lookup:"NAME"`
+	}
+	m := lookup.Map{"KEY": "beef\n", "NAME": "server\r\n"}
+
+	var c conf
+	if err := lookup.Lookup(&c, nil, m); err == nil {
+		t.Error("KEY is not hex without trimming, why no error?!")
+	}
+	var name struct {
+		Name string `lookup:"NAME"`
+	}
+	if err := lookup.Lookup(&name, nil, m); err != nil || name.Name != "server\r\n" {
+		t.Errorf("Unexpected result without trimming: %q/%v", name.Name, err)
+	}
+
+	for _, test := range []struct {
+		name string
+		o    lookup.Options
+		seq  lookup.Looker
+	}{
+		{"Looker", lookup.Options{}, lookup.TrimNewline(m)},
+		{"Options", lookup.Options{TrimNewline: true}, m},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			var c conf
+			if err := test.o.Lookup(&c, nil, test.seq); err != nil {
+				t.Fatalf("Unexpected error: %s", err)
+			}
+			if string(c.Key) != "\xbe\xef" || c.Name != "server" {
+				t.Errorf("Unexpected result: %q/%q", c.Key, c.Name)
+			}
+		})
+	}
+}
@@ -0,0 +1,31 @@
+package lookup
+
+import "strings"
+
+// SourceError is returned by lookers backed by files and objects when they cannot be read or
+// decoded. Use xerrors.As on errors returned by Lookup to find which source failed.
+type SourceError struct {
+	// Source is the file name or URL (e.g, "s3://bucket/key").
+	Source string
+	Err    error
+}
+
+func (e *SourceError) Error() string {
+	return e.Source + ": " + e.Err.Error()
+}
+
+// Unwrap returns Err.
+func (e *SourceError) Unwrap() error {
+	return e.Err
+}
+
+// Errors is returned by Lookup with Options.AllErrors, in the order they were found.
+type Errors []error
+
+func (l Errors) Error() string {
+	msgs := make([]string, len(l))
+	for i, err := range l {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "; ")
+}
@@ -8,30 +8,48 @@ import (
 
 type formLooker struct {
 	*http.Request
+	emptyAs string
 }
 
-// NewForm returns a Looker to access r.Form. Any key present in req but empty is read as "1".
-func NewForm(req *http.Request) Looker {
+// NewForm returns a Looker to access r.Form. Any key present in req but empty is read as "1", so
+// keys like "?verbose" enable bool fields.
+func NewForm(req *http.Request) RequestLooker {
+	return NewFormEmptyAs(req, "1")
+}
+
+// NewFormEmptyAs is like NewForm but keys present in req without values are read as emptyAs. With
+// "", string fields can be set to empty values, but bool fields are false for "?verbose". Either
+// way such keys are found, so they satisfy required fields, and optional fields are not missing.
+func NewFormEmptyAs(req *http.Request, emptyAs string) RequestLooker {
 	return &formLooker{
 		Request: req,
+		emptyAs: emptyAs,
 	}
 }
 
 func (l *formLooker) LookupKey(k string) (string, bool, error) {
 	if err := l.ParseForm(); err != nil {
-		return "", false, xerrors.Errorf("ParseForm failed", err)
+		return "", false, xerrors.Errorf("ParseForm failed: %w", err)
 	}
 
 	v, ok := l.Form[k]
 	if !ok {
 		return "", false, nil
 	}
-	s := "1"
+	return formValue(v, l.emptyAs), true, nil
+}
+
+// Reset replaces the request with req.
+func (l *formLooker) Reset(req *http.Request) {
+	l.Request = req
+}
+
+// formValue returns the first non-empty value, or emptyAs.
+func formValue(v []string, emptyAs string) string {
 	for _, val := range v {
 		if val != "" {
-			s = val
-			break
+			return val
 		}
 	}
-	return s, true, nil
+	return emptyAs
 }
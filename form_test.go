@@ -63,3 +63,29 @@ func TestFormLooker(t *testing.T) {
 		})
 	}
 }
+
+func TestFormLookerEmptyAs(t *testing.T) {
+	var c struct {
+		Name    string `lookup:"name"`
+		Verbose bool   `lookup:"verbose,optional"`
+	}
+	for _, test := range []struct {
+		emptyAs string
+		name    string
+		verbose bool
+	}{
+		{"1", "1", true},
+		{"", "", false},
+	} {
+		req, err := http.NewRequest(http.MethodGet, root+"?name=&verbose", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := lookup.Lookup(&c, nil, lookup.NewFormEmptyAs(req, test.emptyAs)); err != nil {
+			t.Fatalf("Unexpected error for %q: %s", test.emptyAs, err)
+		}
+		if c.Name != test.name || c.Verbose != test.verbose {
+			t.Errorf("Unexpected result for %q: %#v", test.emptyAs, c)
+		}
+	}
+}
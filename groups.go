@@ -0,0 +1,68 @@
+package lookup
+
+import (
+	"fmt"
+	"strings"
+)
+
+// GroupRequirer is implemented by structs passed to Lookup that require sets of keys together,
+// beyond what tags on single fields can tell (e.g, either DATABASE_URL or all of DB_HOST,
+// DB_PORT and DB_NAME).
+type GroupRequirer interface {
+	RequiredGroups() []KeyGroup
+}
+
+// KeyGroup is checked by Lookup after all fields are processed. Keys are like those given to
+// reporters (with prefixes of nested fields but without Options.KeyPrefix), and are present only
+// if found in seq: defaults don't count, and unknown keys are never present.
+type KeyGroup struct {
+	// Name identifies the group in errors.
+	Name string
+	// AnyOf, if not empty, requires at least one of its keys.
+	AnyOf []string
+	// AllOf requires all of its keys once any of them is present (all or none).
+	AllOf []string
+}
+
+// checkGroups checks the groups of e if it is a GroupRequirer.
+func (s *lookupState) checkGroups(e interface{}) error {
+	gr, ok := e.(GroupRequirer)
+	if !ok {
+		return nil
+	}
+	for _, g := range gr.RequiredGroups() {
+		if err := s.fail(s.checkGroup(g)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *lookupState) checkGroup(g KeyGroup) error {
+	if len(g.AnyOf) > 0 && len(s.present(g.AnyOf)) == 0 {
+		return fmt.Errorf("required group %q: missing values for all of %s", g.Name, strings.Join(g.AnyOf, ", "))
+	}
+	present := s.present(g.AllOf)
+	if len(present) == 0 || len(present) == len(g.AllOf) {
+		return nil
+	}
+	var missing []string
+	for _, k := range g.AllOf {
+		if !contains(present, k) {
+			missing = append(missing, k)
+		}
+	}
+	return fmt.Errorf("required group %q: missing values for %s, which are required with %s",
+		g.Name, strings.Join(missing, ", "), strings.Join(present, ", "))
+}
+
+// present returns the keys found in seq.
+func (s *lookupState) present(keys []string) []string {
+	var found []string
+	for _, k := range keys {
+		if s.found[k] {
+			found = append(found, k)
+		}
+	}
+	return found
+}
@@ -0,0 +1,32 @@
+package lookup
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+)
+
+// HostPort is an address like "0.0.0.0:8080", "[::1]:443" or ":8080" (empty host), for fields that
+// Lookup fills in with net.SplitHostPort.
+type HostPort struct {
+	Host string
+	Port int
+}
+
+// String joins Host and Port, with brackets for IPv6 hosts.
+func (h HostPort) String() string {
+	return net.JoinHostPort(h.Host, strconv.Itoa(h.Port))
+}
+
+// parseHostPort splits v and checks that the port is a number from 0 to 65535.
+func parseHostPort(v string) (HostPort, error) {
+	host, port, err := net.SplitHostPort(v)
+	if err != nil {
+		return HostPort{}, err
+	}
+	n, err := strconv.ParseUint(port, 10, 16)
+	if err != nil {
+		return HostPort{}, fmt.Errorf("invalid port %q in address %q", port, v)
+	}
+	return HostPort{Host: host, Port: int(n)}, nil
+}
@@ -0,0 +1,197 @@
+package lookup
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// decodeJSON decodes r, which must hold a single JSON object or null, into data. Numbers are kept as
+// json.Number, so integers beyond 2^53 don't lose precision.
+func decodeJSON(r io.Reader, data *map[string]interface{}) error {
+	dec := json.NewDecoder(r)
+	dec.UseNumber()
+	if err := dec.Decode(data); err != nil {
+		return err
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return errors.New("invalid data after top-level value")
+	}
+	if *data == nil {
+		// A null document has no keys, like an empty object.
+		*data = make(map[string]interface{})
+	}
+	return nil
+}
+
+// stripJSONC replaces comments in b with spaces and removes trailing commas before "}" and "]",
+// leaving strings untouched.
+func stripJSONC(b []byte) []byte {
+	out := make([]byte, 0, len(b))
+	// Index in out of the last comma outside strings, while only spaces follow it.
+	comma := -1
+	for i := 0; i < len(b); i++ {
+		c := b[i]
+		switch {
+		case c == '"':
+			start := i
+			for i++; i < len(b) && b[i] != '"'; i++ {
+				if b[i] == '\\' {
+					i++
+				}
+			}
+			if i >= len(b) {
+				i = len(b) - 1
+			}
+			out = append(out, b[start:i+1]...)
+			comma = -1
+			continue
+		case c == '/' && i+1 < len(b) && b[i+1] == '/':
+			for i < len(b) && b[i] != '\n' {
+				i++
+			}
+			out = append(out, '\n')
+			continue
+		case c == '/' && i+1 < len(b) && b[i+1] == '*':
+			end := bytes.Index(b[i+2:], []byte("*/"))
+			if end < 0 {
+				end = len(b) - i - 2
+			}
+			i += end + 3
+			out = append(out, ' ')
+			continue
+		case c == '}' || c == ']':
+			if comma >= 0 {
+				out[comma] = ' '
+			}
+		case c == ',':
+			out = append(out, c)
+			comma = len(out) - 1
+			continue
+		}
+		if c != ' ' && c != '\t' && c != '\n' && c != '\r' {
+			comma = -1
+		}
+		out = append(out, c)
+	}
+	return out
+}
+
+// jsonLookup returns the value of k in data, converted by jsonString. Keys starting with "/" are
+// JSON Pointers (RFC 6901) into nested objects and arrays, e.g. "/user/address/0/zip". Null
+// values are not found, so defaults apply.
+func jsonLookup(data map[string]interface{}, k string) (string, bool) {
+	v, ok := jsonNode(data, k)
+	if !ok {
+		return "", false
+	}
+	return jsonString(v), true
+}
+
+// jsonRawLookup is like jsonLookup but returns the value encoded as JSON, for RawJSONLooker.
+func jsonRawLookup(data map[string]interface{}, k string) (json.RawMessage, bool, error) {
+	v, ok := jsonNode(data, k)
+	if !ok {
+		return nil, false, nil
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, false, err
+	}
+	return b, true, nil
+}
+
+// jsonNode returns the value of k in data, which is a key or a JSON Pointer, unless it is null.
+func jsonNode(data map[string]interface{}, k string) (interface{}, bool) {
+	if !strings.HasPrefix(k, "/") {
+		v, ok := data[k]
+		return v, ok && v != nil
+	}
+
+	var v interface{} = data
+	for _, segment := range strings.Split(k[1:], "/") {
+		segment = unescapePointer(segment)
+		switch node := v.(type) {
+		case map[string]interface{}:
+			var ok bool
+			if v, ok = node[segment]; !ok {
+				return nil, false
+			}
+		case []interface{}:
+			i, err := strconv.Atoi(segment)
+			if err != nil || i < 0 || i >= len(node) || (segment[0] == '0' && len(segment) > 1) {
+				return nil, false
+			}
+			v = node[i]
+		default:
+			return nil, false
+		}
+	}
+	return v, v != nil
+}
+
+// unescapePointer decodes "~1" and "~0" in a segment of a JSON Pointer.
+func unescapePointer(segment string) string {
+	return strings.Replace(strings.Replace(segment, "~1", "/", -1), "~0", "~", -1)
+}
+
+// pointerRoot returns the top-level key read by k if it is a JSON Pointer, e.g. "user" for
+// "/user/zip".
+func pointerRoot(k string) (string, bool) {
+	if !strings.HasPrefix(k, "/") {
+		return "", false
+	}
+	return unescapePointer(strings.SplitN(k[1:], "/", 2)[0]), true
+}
+
+// jsonItems returns the items of v, converted by jsonString, if it is a JSON array (e.g, from
+// NewJSONFile). Null items are empty.
+func jsonItems(v string) ([]string, bool) {
+	if !strings.HasPrefix(v, "[") || !json.Valid([]byte(v)) {
+		return nil, false
+	}
+	dec := json.NewDecoder(strings.NewReader(v))
+	dec.UseNumber()
+	var l []interface{}
+	if err := dec.Decode(&l); err != nil {
+		return nil, false
+	}
+	items := make([]string, len(l))
+	for i, item := range l {
+		if item != nil {
+			items[i] = jsonString(item)
+		}
+	}
+	return items, true
+}
+
+// jsonString converts a decoded JSON value to the string returned by LookupKey. Numbers keep their
+// digits but not exponents, so large integers can be loaded into int fields. Arrays and objects
+// are encoded back to JSON, so they can be loaded into slices (see jsonItems) and structs.
+func jsonString(v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case bool:
+		return strconv.FormatBool(v)
+	case json.Number:
+		if strings.ContainsAny(string(v), "eE") {
+			if f, err := v.Float64(); err == nil {
+				return strconv.FormatFloat(f, 'f', -1, 64)
+			}
+		}
+		return v.String()
+	case []interface{}, map[string]interface{}:
+		b, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Sprint(v)
+		}
+		return string(b)
+	default:
+		return fmt.Sprint(v)
+	}
+}
@@ -1,50 +1,148 @@
 package lookup
 
 import (
+	"bytes"
 	"encoding/json"
-	"fmt"
+	"io/ioutil"
 	"os"
 	"sync"
 )
 
 type jsonLooker struct {
 	filename string
+	optional bool
+	jsonc    bool
+	// Files merged over filename, which can be missing.
+	overlays []string
 
 	mutex sync.Mutex
 	data  map[string]interface{}
+	err   error
 }
 
 // NewJSONFile returns a Looker that can extracts data from JSON file. File is loaded only once.
+// Keys starting with "/" are JSON Pointers (e.g, "/servers/0/host"), which also work with the other
+// JSON lookers.
 func NewJSONFile(filename string) Looker {
 	return &jsonLooker{
 		filename: filename,
 	}
 }
 
+// NewJSONCFile is like NewJSONFile but the file can have "//" and "/* */" comments, and trailing
+// commas in objects and arrays, as usual in configuration edited by people.
+func NewJSONCFile(filename string) Looker {
+	return &jsonLooker{
+		filename: filename,
+		jsonc:    true,
+	}
+}
+
+// NewJSONFileOptional is like NewJSONFile but a missing file has no keys instead of being an error.
+func NewJSONFileOptional(filename string) Looker {
+	return &jsonLooker{
+		filename: filename,
+		optional: true,
+	}
+}
+
+// NewJSONFiles returns a Looker for the JSON files merged in order: objects are merged
+// recursively and other values of later files replace those of earlier ones (e.g, "base.json"
+// then "prod.json"). The first file is required, the others can be missing. Files are loaded
+// only once.
+func NewJSONFiles(filename string, overlays ...string) Looker {
+	return &jsonLooker{
+		filename: filename,
+		overlays: overlays,
+	}
+}
+
 func (l *jsonLooker) LookupKey(k string) (string, bool, error) {
+	if err := l.once(); err != nil {
+		return "", false, err
+	}
+
+	v, ok := jsonLookup(l.data, k)
+	return v, ok, nil
+}
+
+// LookupRawJSON returns the value of k encoded as JSON.
+func (l *jsonLooker) LookupRawJSON(k string) (json.RawMessage, bool, error) {
+	if err := l.once(); err != nil {
+		return nil, false, err
+	}
+	return jsonRawLookup(l.data, k)
+}
+
+// Keys returns the keys in the file, which is loaded if needed.
+func (l *jsonLooker) Keys() []string {
+	l.once()
+	return mapKeys(l.data)
+}
+
+// KeysErr is like Keys, but also returns the error loading the file.
+func (l *jsonLooker) KeysErr() ([]string, error) {
+	if err := l.once(); err != nil {
+		return nil, err
+	}
+	return mapKeys(l.data), nil
+}
+
+func (l *jsonLooker) once() error {
 	l.mutex.Lock()
+	defer l.mutex.Unlock()
 	if l.data == nil {
 		// If file fails to load, don't try again for the same instance:
 		l.data = make(map[string]interface{})
+		l.err = l.load()
+	}
+	return l.err
+}
 
-		f, err := os.Open(l.filename)
-		if err != nil {
-			l.mutex.Unlock()
-			return "", false, err
+func (l *jsonLooker) load() error {
+	if err := readJSONFile(l.filename, l.optional, l.jsonc, &l.data); err != nil {
+		return &SourceError{Source: l.filename, Err: err}
+	}
+	for _, filename := range l.overlays {
+		var overlay map[string]interface{}
+		if err := readJSONFile(filename, true, l.jsonc, &overlay); err != nil {
+			return &SourceError{Source: filename, Err: err}
 		}
+		mergeJSON(l.data, overlay)
+	}
+	return nil
+}
 
-		err = json.NewDecoder(f).Decode(&l.data)
-		f.Close()
-		if err != nil {
-			l.mutex.Unlock()
-			return "", false, err
-		}
+// readJSONFile decodes filename into data. If optional, a missing file leaves data unchanged.
+func readJSONFile(filename string, optional, jsonc bool, data *map[string]interface{}) error {
+	f, err := os.Open(filename)
+	if optional && os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
 	}
-	l.mutex.Unlock()
+	defer f.Close()
+	if !jsonc {
+		return decodeJSON(f, data)
+	}
+	b, err := ioutil.ReadAll(f)
+	if err != nil {
+		return err
+	}
+	return decodeJSON(bytes.NewReader(stripJSONC(b)), data)
+}
 
-	v, ok := l.data[k]
-	if !ok {
-		return "", false, nil
+// mergeJSON merges overlay into data recursively: objects in both are merged, other values in
+// overlay replace those in data.
+func mergeJSON(data, overlay map[string]interface{}) {
+	for k, v := range overlay {
+		if o, ok := v.(map[string]interface{}); ok {
+			if d, ok := data[k].(map[string]interface{}); ok {
+				mergeJSON(d, o)
+				continue
+			}
+		}
+		data[k] = v
 	}
-	return fmt.Sprint(v), true, nil
 }
@@ -0,0 +1,70 @@
+package lookup
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+)
+
+type jsonReaderLooker struct {
+	r io.Reader
+
+	mutex sync.Mutex
+	data  map[string]interface{}
+	err   error
+}
+
+// NewJSONReader returns a Looker that extracts data from JSON read from r, which is read only once
+// and closed if it is an io.Closer.
+func NewJSONReader(r io.Reader) Looker {
+	return &jsonReaderLooker{
+		r: r,
+	}
+}
+
+func (l *jsonReaderLooker) LookupKey(k string) (string, bool, error) {
+	if err := l.load(); err != nil {
+		return "", false, err
+	}
+
+	v, ok := jsonLookup(l.data, k)
+	return v, ok, nil
+}
+
+// LookupRawJSON returns the value of k encoded as JSON.
+func (l *jsonReaderLooker) LookupRawJSON(k string) (json.RawMessage, bool, error) {
+	if err := l.load(); err != nil {
+		return nil, false, err
+	}
+	return jsonRawLookup(l.data, k)
+}
+
+// Keys returns the keys in the JSON, which is read if needed.
+func (l *jsonReaderLooker) Keys() []string {
+	l.load()
+	return mapKeys(l.data)
+}
+
+// KeysErr is like Keys, but also returns the error loading the JSON.
+func (l *jsonReaderLooker) KeysErr() ([]string, error) {
+	if err := l.load(); err != nil {
+		return nil, err
+	}
+	return mapKeys(l.data), nil
+}
+
+func (l *jsonReaderLooker) load() error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if l.data != nil {
+		return l.err
+	}
+	// If r fails to load, don't try again for the same instance:
+	l.data = make(map[string]interface{})
+
+	l.err = decodeJSON(l.r, &l.data)
+	if c, ok := l.r.(io.Closer); ok {
+		c.Close()
+	}
+	return l.err
+}
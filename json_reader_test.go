@@ -0,0 +1,217 @@
+package lookup_test
+
+import (
+	"encoding/json"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/carloslenz/lookup"
+)
+
+type closeRecorder struct {
+	io.Reader
+	closed int
+}
+
+func (r *closeRecorder) Close() error {
+	r.closed++
+	return nil
+}
+
+func TestJSONReader(t *testing.T) {
+	r := &closeRecorder{Reader: strings.NewReader(`{"PORT": 8080, "HOST": "localhost"}`)}
+	l := lookup.NewJSONReader(r)
+	for _, k := range []string{"PORT", "HOST", "OTHER"} {
+		if _, _, err := l.LookupKey(k); err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+	}
+	if v, ok, _ := l.LookupKey("PORT"); v != "8080" || !ok {
+		t.Errorf("Unexpected result: got %q/%t, expecting %q/true", v, ok, "8080")
+	}
+	if r.closed != 1 {
+		t.Errorf("Reader closed %d times, expecting once", r.closed)
+	}
+
+	invalid := lookup.NewJSONReader(strings.NewReader(`{"PORT": `))
+	for i := 0; i < 2; i++ {
+		if _, _, err := invalid.LookupKey("PORT"); err == nil {
+			t.Error("JSON is invalid, why no error?!")
+		}
+	}
+}
+
+func TestJSONReaderSliceOfStructs(t *testing.T) {
+	type server struct {
+		Host string `json:"host"`
+		Port int    `json:"port"`
+	}
+	var c struct {
+		Servers []server  `json:"servers"`
+		Backup  []*server `json:"backup,omitempty"`
+	}
+	r := strings.NewReader(`{"servers": [{"host": "a", "port": 1}, {"host": "b", "port": 2}]}`)
+	var e entries
+	if err := lookup.Lookup(&c, &e, lookup.NewJSONReader(r)); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	expected := []server{{"a", 1}, {"b", 2}}
+	if !reflect.DeepEqual(c.Servers, expected) || c.Backup != nil {
+		t.Errorf("Unexpected result: %#v", c)
+	}
+	if expected := (entries{"servers", "(2 items)", "backup", ""}); !reflect.DeepEqual(e, expected) {
+		t.Errorf("Unexpected reports: %#v, expecting %#v", e, expected)
+	}
+
+	r = strings.NewReader(`{"servers": [{"host": 1}]}`)
+	if err := lookup.Lookup(&c, nil, lookup.NewJSONReader(r)); err == nil {
+		t.Error("Host is not a string, why no error?!")
+	}
+}
+
+func TestJSONReaderSlices(t *testing.T) {
+	var c struct {
+		Tags   []string        `json:"tags"`
+		Ports  []int           `json:"ports"`
+		Pair   [2]string       `json:"pair"`
+		Delays []time.Duration `json:"delays"`
+		Words  []string        `json:"words"`
+	}
+	r := strings.NewReader(`{"tags": ["a", "b,c"], "ports": [80, 9007199254740993], "pair": ["x", null],
+		"delays": ["1s", 5], "words": "d,e"}`)
+	if err := lookup.Lookup(&c, nil, lookup.NewJSONReader(r)); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if expected := []string{"a", "b,c"}; !reflect.DeepEqual(c.Tags, expected) {
+		t.Errorf("Unexpected tags: got %q, expecting %q", c.Tags, expected)
+	}
+	if expected := []int{80, 9007199254740993}; !reflect.DeepEqual(c.Ports, expected) {
+		t.Errorf("Unexpected ports: got %v, expecting %v", c.Ports, expected)
+	}
+	if expected := [2]string{"x", ""}; c.Pair != expected {
+		t.Errorf("Unexpected pair: got %q, expecting %q", c.Pair, expected)
+	}
+	if expected := []time.Duration{time.Second, 5}; !reflect.DeepEqual(c.Delays, expected) {
+		t.Errorf("Unexpected delays: got %v, expecting %v", c.Delays, expected)
+	}
+	if expected := []string{"d", "e"}; !reflect.DeepEqual(c.Words, expected) {
+		t.Errorf("Unexpected words: got %q, expecting %q", c.Words, expected)
+	}
+
+	var p struct {
+		Ports []int `json:"ports"`
+	}
+	r = strings.NewReader(`{"ports": [80, "http"]}`)
+	if err := lookup.Lookup(&p, nil, lookup.NewJSONReader(r)); err == nil || !strings.Contains(err.Error(), "item 1") {
+		t.Errorf("Port is not a number, unexpected error: %v", err)
+	}
+}
+
+func TestJSONReaderPointer(t *testing.T) {
+	l := lookup.NewJSONReader(strings.NewReader(`{
+		"user": {"address": [{"zip": "12345"}, {"zip": 67890}], "a/b": {"m~n": true}},
+		"flat": "x"
+	}`))
+	tests := []struct {
+		key, val string
+		found    bool
+	}{
+		{"/user/address/0/zip", "12345", true},
+		{"/user/address/1/zip", "67890", true},
+		{"/user/a~1b/m~0n", "true", true},
+		{"/flat", "x", true},
+		{"/user/address/2/zip", "", false},
+		{"/user/address/01/zip", "", false},
+		{"/user/name", "", false},
+		{"/flat/more", "", false},
+	}
+	for _, test := range tests {
+		t.Run(test.key, func(t *testing.T) {
+			v, ok, err := l.LookupKey(test.key)
+			if v != test.val || ok != test.found || err != nil {
+				t.Errorf("Unexpected result: got %q/%t/%v, expecting %q/%t", v, ok, err, test.val, test.found)
+			}
+		})
+	}
+}
+
+func TestJSONReaderPointerStrict(t *testing.T) {
+	var c struct {
+		Port int    `json:"port"`
+		Zip  string `json:"/user/zip"`
+		Key  string `json:"/a~1b/key"`
+	}
+	r := strings.NewReader(`{"port": 80, "user": {"zip": "12345"}, "a/b": {"key": "v"}}`)
+	if err := (lookup.Options{Strict: true}).Lookup(&c, nil, lookup.NewJSONReader(r)); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if c.Zip != "12345" || c.Key != "v" {
+		t.Errorf("Unexpected result: %#v", c)
+	}
+
+	r = strings.NewReader(`{"port": 80, "user": {"zip": "12345"}, "a/b": {"key": "v"}, "typo": 1}`)
+	if err := (lookup.Options{Strict: true}).Lookup(&c, nil, lookup.NewJSONReader(r)); err == nil {
+		t.Error("typo is unknown, why no error?!")
+	}
+}
+
+func TestJSONReaderNull(t *testing.T) {
+	var c struct {
+		Timeout string `json:"timeout,omitempty"`
+		Port    int    `json:"port"`
+		Debug   *bool  `json:"debug,omitempty"`
+		Zip     string `json:"/user/zip,omitempty"`
+	}
+	r := strings.NewReader(`{"timeout": null, "port": null, "debug": null, "user": {"zip": null}}`)
+	defaults := lookup.Map{"timeout": "30s", "port": "80", "/user/zip": "00000"}
+	if err := lookup.Lookup(&c, nil, lookup.NewJSONReader(r), defaults); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if c.Timeout != "30s" || c.Port != 80 || c.Debug != nil || c.Zip != "00000" {
+		t.Errorf("Unexpected result: %#v", c)
+	}
+}
+
+func TestJSONReaderRawMessage(t *testing.T) {
+	type conf struct {
+		Plugin json.RawMessage `lookup:"plugin"`
+		Name   json.RawMessage `lookup:"name"`
+		Count  json.RawMessage `lookup:"count,optional"`
+	}
+	r := strings.NewReader(`{"plugin": {"kind": "s3", "args": ["a", 1]}, "name": "123"}`)
+	var c conf
+	var e entries
+	seq := []lookup.Looker{
+		lookup.OnError(lookup.NewJSONReader(r), lookup.FailFast),
+		lookup.Map{"count": "2", "name": "other"},
+	}
+	if err := lookup.Lookup(&c, &e, seq...); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	for _, test := range []struct {
+		got      json.RawMessage
+		expected string
+	}{
+		{c.Plugin, `{"args":["a",1],"kind":"s3"}`},
+		{c.Name, `"123"`},
+		{c.Count, `2`},
+	} {
+		if string(test.got) != test.expected {
+			t.Errorf("Unexpected raw JSON: got %s, expecting %s", test.got, test.expected)
+		}
+	}
+	expected := entries{"plugin", "(28 bytes)", "name", "(5 bytes)", "count", "(1 bytes)"}
+	if !reflect.DeepEqual(e, expected) {
+		t.Errorf("Unexpected reports: %#v, expecting %#v", e, expected)
+	}
+
+	if err := lookup.Lookup(&c, nil, lookup.Map{"plugin": "not json", "name": "x"}); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if string(c.Plugin) != `"not json"` {
+		t.Errorf("Unexpected raw JSON for string: %s", c.Plugin)
+	}
+}
@@ -2,7 +2,6 @@ package lookup
 
 import (
 	"encoding/json"
-	"fmt"
 	"net/http"
 	"sync"
 )
@@ -10,35 +9,77 @@ import (
 type jsonRequestLooker struct {
 	*http.Request
 
-	mutex sync.Mutex
-	data  map[string]interface{}
+	mutex  sync.Mutex
+	data   map[string]interface{}
+	loaded bool
+	err    error
 }
 
 // NewJSONRequest returns a Looker to access r.Body.
-func NewJSONRequest(req *http.Request) Looker {
+func NewJSONRequest(req *http.Request) RequestLooker {
 	return &jsonRequestLooker{
 		Request: req,
 	}
 }
 
 func (l *jsonRequestLooker) LookupKey(k string) (string, bool, error) {
+	if err := l.once(); err != nil {
+		return "", false, err
+	}
+
+	v, ok := jsonLookup(l.data, k)
+	return v, ok, nil
+}
+
+// LookupRawJSON returns the value of k encoded as JSON.
+func (l *jsonRequestLooker) LookupRawJSON(k string) (json.RawMessage, bool, error) {
+	if err := l.once(); err != nil {
+		return nil, false, err
+	}
+	return jsonRawLookup(l.data, k)
+}
+
+// Keys returns the keys in the body, which is loaded if needed.
+func (l *jsonRequestLooker) Keys() []string {
+	l.once()
+	return mapKeys(l.data)
+}
+
+// KeysErr is like Keys, but also returns the error loading the body.
+func (l *jsonRequestLooker) KeysErr() ([]string, error) {
+	if err := l.once(); err != nil {
+		return nil, err
+	}
+	return mapKeys(l.data), nil
+}
+
+// Reset clears the values loaded from the previous request and replaces it with req.
+func (l *jsonRequestLooker) Reset(req *http.Request) {
 	l.mutex.Lock()
-	if l.data == nil {
-		// If body fails to load, don't try again for the same instance:
-		l.data = make(map[string]interface{})
-
-		err := json.NewDecoder(l.Body).Decode(&l.data)
-		l.Body.Close()
-		if err != nil {
-			l.mutex.Unlock()
-			return "", false, err
-		}
+	defer l.mutex.Unlock()
+	l.Request = req
+	for k := range l.data {
+		delete(l.data, k)
 	}
-	l.mutex.Unlock()
+	l.loaded = false
+	l.err = nil
+}
 
-	v, ok := l.data[k]
-	if !ok {
-		return "", false, nil
+func (l *jsonRequestLooker) once() error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if !l.loaded {
+		// If body fails to load, don't try again for the same request:
+		l.loaded = true
+		if l.data == nil {
+			l.data = make(map[string]interface{})
+		}
+		l.err = l.load()
 	}
-	return fmt.Sprint(v), true, nil
+	return l.err
+}
+
+func (l *jsonRequestLooker) load() error {
+	defer l.Body.Close()
+	return decodeJSON(l.Body, &l.data)
 }
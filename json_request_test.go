@@ -0,0 +1,85 @@
+package lookup_test
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/carloslenz/lookup"
+)
+
+const benchBody = `{"name": "lorem ipsum", "port": 8080, "debug": true, "tags": ["a", "b"], "ratio": 0.25}`
+
+func BenchmarkJSONRequest(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		req, _ := http.NewRequest(http.MethodPost, "/", strings.NewReader(benchBody))
+		l := lookup.NewJSONRequest(req)
+		for _, k := range []string{"name", "port", "debug", "missing"} {
+			if _, _, err := l.LookupKey(k); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
+
+func TestJSONRequestValues(t *testing.T) {
+	body := `{"s": "text", "b": false, "n": 12345678901, "big": 9007199254740993, "e": 1e3, "f": 0.25, "l": [1, 2]}`
+	req, err := http.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("Cannot create request: %s", err)
+	}
+	l := lookup.NewJSONRequest(req)
+	tests := []struct {
+		key, val string
+	}{
+		{"s", "text"},
+		{"b", "false"},
+		{"n", "12345678901"},
+		{"big", "9007199254740993"},
+		{"e", "1000"},
+		{"f", "0.25"},
+		{"l", "[1,2]"},
+	}
+	for _, test := range tests {
+		t.Run(test.key, func(t *testing.T) {
+			v, got, err := l.LookupKey(test.key)
+			if v != test.val || !got || err != nil {
+				t.Errorf("Unexpected result: got %q/%t/%v, expecting %q", v, got, err, test.val)
+			}
+		})
+	}
+}
+
+func TestJSONRequestReset(t *testing.T) {
+	newRequest := func(body string) *http.Request {
+		req, err := http.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+		if err != nil {
+			t.Fatalf("Cannot create request: %s", err)
+		}
+		return req
+	}
+	l := lookup.NewJSONRequest(newRequest(`{"a": "1", "b": "2"}`))
+	if v, ok, err := l.LookupKey("a"); v != "1" || !ok || err != nil {
+		t.Errorf("Unexpected result: got %q/%t/%v, expecting %q", v, ok, err, "1")
+	}
+
+	l.Reset(newRequest(`{"a": "3"}`))
+	if v, ok, err := l.LookupKey("a"); v != "3" || !ok || err != nil {
+		t.Errorf("Unexpected result after Reset: got %q/%t/%v, expecting %q", v, ok, err, "3")
+	}
+	if v, ok, _ := l.LookupKey("b"); ok {
+		t.Errorf("Value from previous request is still cached: %q", v)
+	}
+
+	l.Reset(newRequest(`{"a": `))
+	for i := 0; i < 2; i++ {
+		if _, _, err := l.LookupKey("a"); err == nil {
+			t.Error("Body is invalid, why no error?!")
+		}
+	}
+	l.Reset(newRequest(`{"a": "4"}`))
+	if v, ok, err := l.LookupKey("a"); v != "4" || !ok || err != nil {
+		t.Errorf("Unexpected result after Reset: got %q/%t/%v, expecting %q", v, ok, err, "4")
+	}
+}
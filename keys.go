@@ -0,0 +1,175 @@
+package lookup
+
+import (
+	"os"
+	"strings"
+	"unicode"
+)
+
+type transformLooker struct {
+	Looker
+	keyFn func(string) string
+}
+
+// NewTransform returns a Looker that looks up keyFn(key) in l, so that the same tags can be used
+// with sources that have different naming conventions (e.g, NewTransform(Env, ToScreamingSnake)).
+func NewTransform(l Looker, keyFn func(string) string) Looker {
+	return &transformLooker{
+		Looker: l,
+		keyFn:  keyFn,
+	}
+}
+
+func (l *transformLooker) LookupKey(k string) (string, bool, error) {
+	return l.Looker.LookupKey(l.keyFn(k))
+}
+
+type envOverrideLooker struct {
+	inner Looker
+	keyFn func(string) string
+}
+
+// OverrideWithEnv returns a Looker that tries the environment variable keyFn(key) before looking up
+// key in inner (e.g, OverrideWithEnv(NewJSONFile(name), ToScreamingSnake)). A nil keyFn is the
+// identity. Keys are those of inner, if it is Enumerable.
+func OverrideWithEnv(inner Looker, keyFn func(string) string) Looker {
+	return &envOverrideLooker{
+		inner: inner,
+		keyFn: keyFn,
+	}
+}
+
+func (l *envOverrideLooker) LookupKey(k string) (string, bool, error) {
+	env := k
+	if l.keyFn != nil {
+		env = l.keyFn(k)
+	}
+	if v, ok := os.LookupEnv(env); ok {
+		return v, true, nil
+	}
+	return l.inner.LookupKey(k)
+}
+
+// Keys returns the keys of inner, or nil if it is not Enumerable.
+func (l *envOverrideLooker) Keys() []string {
+	keys, _ := keysOf(l.inner)
+	return keys
+}
+
+// KeysErr is like Keys, but also returns the error loading the keys of inner.
+func (l *envOverrideLooker) KeysErr() ([]string, error) {
+	return keysOf(l.inner)
+}
+
+type normalizeLooker struct {
+	Looker
+	foldCase bool
+}
+
+// NormalizeKeys returns a Looker that finds keys in l regardless of separators, so "db-host",
+// "db.host" and "db_host" are the same key. With foldCase, case is ignored too (e.g, "DB_HOST").
+// Keys are compared in canonical form: separators ("-", "." and "_") replaced with "_", and
+// lowercase if foldCase. Exact matches are tried first. When l is Enumerable its keys are
+// compared, otherwise (e.g, Env) the key is tried with each separator (and in upper and lower case
+// if foldCase). The result is not Enumerable, so Options.Strict doesn't check its keys.
+func NormalizeKeys(l Looker, foldCase bool) Looker {
+	return &normalizeLooker{
+		Looker:   l,
+		foldCase: foldCase,
+	}
+}
+
+func (l *normalizeLooker) LookupKey(k string) (string, bool, error) {
+	if v, ok, err := l.Looker.LookupKey(k); ok || err != nil {
+		return v, ok, err
+	}
+	canonical := l.canonical(k)
+	if en, ok := l.Looker.(Enumerable); ok {
+		for _, key := range en.Keys() {
+			if l.canonical(key) == canonical {
+				return l.Looker.LookupKey(key)
+			}
+		}
+		return "", false, nil
+	}
+
+	for _, sep := range []string{"_", "-", "."} {
+		key := strings.Replace(canonical, "_", sep, -1)
+		variants := []string{key}
+		if l.foldCase {
+			variants = append(variants, strings.ToUpper(key))
+		}
+		for _, key := range variants {
+			if key == k {
+				continue
+			}
+			if v, ok, err := l.Looker.LookupKey(key); ok || err != nil {
+				return v, ok, err
+			}
+		}
+	}
+	return "", false, nil
+}
+
+// canonical replaces separators in k with "_" and, if foldCase, lowercases it.
+func (l *normalizeLooker) canonical(k string) string {
+	k = strings.NewReplacer("-", "_", ".", "_").Replace(k)
+	if l.foldCase {
+		k = strings.ToLower(k)
+	}
+	return k
+}
+
+// ToScreamingSnake converts names like "DBHost", "dbHost" or "db-host" to "DB_HOST".
+func ToScreamingSnake(s string) string {
+	return strings.ToUpper(strings.Join(splitWords(s), "_"))
+}
+
+// ToCamel converts names like "DB_HOST", "DBHost" or "db-host" to "dbHost".
+func ToCamel(s string) string {
+	words := splitWords(s)
+	for i, w := range words {
+		w = strings.ToLower(w)
+		if i > 0 {
+			w = strings.ToUpper(w[:1]) + w[1:]
+		}
+		words[i] = w
+	}
+	return strings.Join(words, "")
+}
+
+// ToKebab converts names like "DB_HOST", "DBHost" or "dbHost" to "db-host".
+func ToKebab(s string) string {
+	return strings.ToLower(strings.Join(splitWords(s), "-"))
+}
+
+// splitWords splits s at separators ("-", "_", "." and spaces) and at case changes, keeping
+// acronyms together ("HTTPPort" is "HTTP", "Port").
+func splitWords(s string) []string {
+	var words []string
+	var word []rune
+	flush := func() {
+		if len(word) > 0 {
+			words = append(words, string(word))
+			word = nil
+		}
+	}
+
+	runes := []rune(s)
+	for i, r := range runes {
+		switch {
+		case r == '-' || r == '_' || r == '.' || unicode.IsSpace(r):
+			flush()
+			continue
+		case unicode.IsUpper(r) && i > 0:
+			prev := runes[i-1]
+			nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if unicode.IsLower(prev) || unicode.IsDigit(prev) || (unicode.IsUpper(prev) && nextLower) {
+				flush()
+			}
+		}
+		word = append(word, r)
+	}
+	flush()
+	return words
+}
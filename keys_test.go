@@ -0,0 +1,98 @@
+package lookup_test
+
+import (
+	"os"
+	"testing"
+
+	"github.com/carloslenz/lookup"
+)
+
+func TestKeyTransforms(t *testing.T) {
+	tests := []struct {
+		in, snake, camel, kebab string
+	}{
+		{"Port", "PORT", "port", "port"},
+		{"DBHost", "DB_HOST", "dbHost", "db-host"},
+		{"dbHost", "DB_HOST", "dbHost", "db-host"},
+		{"HTTPPort2", "HTTP_PORT2", "httpPort2", "http-port2"},
+		{"db-host", "DB_HOST", "dbHost", "db-host"},
+		{"db.host", "DB_HOST", "dbHost", "db-host"},
+		{"DB_HOST", "DB_HOST", "dbHost", "db-host"},
+	}
+	for _, test := range tests {
+		t.Run(test.in, func(t *testing.T) {
+			if s := lookup.ToScreamingSnake(test.in); s != test.snake {
+				t.Errorf("Unexpected ToScreamingSnake: got %q, expecting %q", s, test.snake)
+			}
+			if s := lookup.ToCamel(test.in); s != test.camel {
+				t.Errorf("Unexpected ToCamel: got %q, expecting %q", s, test.camel)
+			}
+			if s := lookup.ToKebab(test.in); s != test.kebab {
+				t.Errorf("Unexpected ToKebab: got %q, expecting %q", s, test.kebab)
+			}
+		})
+	}
+}
+
+func TestTransform(t *testing.T) {
+	var c struct {
+		DBHost string `lookup:"db_host"`
+		DBPort int    `lookup:"db_port"`
+		DBName string `lookup:"db_name"`
+	}
+	env := lookup.NewTransform(lookup.Map{"DB_HOST": "localhost"}, lookup.ToScreamingSnake)
+	json := lookup.NewTransform(lookup.Map{"dbPort": "5432"}, lookup.ToCamel)
+	args := lookup.NewTransform(lookup.NewArgs("--", []string{"--db-name=app"}), lookup.ToKebab)
+	if err := lookup.Lookup(&c, nil, env, json, args); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if c.DBHost != "localhost" || c.DBPort != 5432 || c.DBName != "app" {
+		t.Errorf("Unexpected result: %#v", c)
+	}
+}
+
+func TestOverrideWithEnv(t *testing.T) {
+	const env = "LOOKUP_TEST_DB_HOST"
+	os.Setenv(env, "db.example.com")
+	defer os.Unsetenv(env)
+
+	var c struct {
+		DBHost string `lookup:"lookup.test.db.host"`
+		DBPort int    `lookup:"lookup.test.db.port"`
+	}
+	file := lookup.Map{"lookup.test.db.host": "localhost", "lookup.test.db.port": "5432"}
+	l := lookup.OverrideWithEnv(file, lookup.ToScreamingSnake)
+	if err := (lookup.Options{Strict: true}).Lookup(&c, nil, l); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if c.DBHost != "db.example.com" || c.DBPort != 5432 {
+		t.Errorf("Unexpected result: %#v", c)
+	}
+}
+
+func TestNormalizeKeys(t *testing.T) {
+	var c struct {
+		DBHost string `lookup:"db_host"`
+		DBPort int    `lookup:"db.port"`
+		DBName string `lookup:"db-name"`
+	}
+	args := lookup.NormalizeKeys(lookup.NewArgs("--", []string{"--db-host=localhost"}), false)
+	env := lookup.NormalizeKeys(lookup.LookerFunc(func(k string) (string, bool, error) {
+		if k == "DB_PORT" {
+			return "5432", true, nil
+		}
+		return "", false, nil
+	}), true)
+	json := lookup.NormalizeKeys(lookup.Map{"db.name": "app"}, false)
+	if err := lookup.Lookup(&c, nil, args, env, json); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if c.DBHost != "localhost" || c.DBPort != 5432 || c.DBName != "app" {
+		t.Errorf("Unexpected result: %#v", c)
+	}
+
+	l := lookup.NormalizeKeys(lookup.Map{"DB_HOST": "localhost"}, false)
+	if _, ok, _ := l.LookupKey("db-host"); ok {
+		t.Error("Case is not folded, why found?!")
+	}
+}
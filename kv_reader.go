@@ -0,0 +1,121 @@
+package lookup
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+
+	"golang.org/x/xerrors"
+)
+
+type kvReaderLooker struct {
+	r io.Reader
+
+	mutex sync.Mutex
+	data  Map
+	err   error
+}
+
+// NewKVReader returns a Looker for KEY=VALUE lines read from r (e.g, os.Stdin), which is read
+// only once, on the first lookup, and closed if it is an io.Closer. Empty lines and lines
+// starting with "#" are ignored, as are comments after unquoted values. Values can be quoted
+// with '...' (kept as is) or "..." (Go escapes, like DotEnvReporter writes).
+func NewKVReader(r io.Reader) Looker {
+	return &kvReaderLooker{
+		r: r,
+	}
+}
+
+func (l *kvReaderLooker) LookupKey(k string) (string, bool, error) {
+	if err := l.load(); err != nil {
+		return "", false, err
+	}
+	return l.data.LookupKey(k)
+}
+
+// Keys returns the keys read, which are read if needed.
+func (l *kvReaderLooker) Keys() []string {
+	l.load()
+	return l.data.Keys()
+}
+
+// KeysErr is like Keys, but also returns the error loading the input.
+func (l *kvReaderLooker) KeysErr() ([]string, error) {
+	if err := l.load(); err != nil {
+		return nil, err
+	}
+	return l.data.Keys(), nil
+}
+
+func (l *kvReaderLooker) load() error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if l.data != nil {
+		return l.err
+	}
+	// If r fails to load, don't try again for the same instance:
+	l.data = make(Map)
+
+	l.err = parseKV(l.r, l.data)
+	if c, ok := l.r.(io.Closer); ok {
+		c.Close()
+	}
+	return l.err
+}
+
+// parseKV reads KEY=VALUE lines from r into data.
+func parseKV(r io.Reader, data Map) error {
+	sc := bufio.NewScanner(r)
+	for n := 1; sc.Scan(); n++ {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		i := strings.Index(line, "=")
+		if i <= 0 {
+			return fmt.Errorf("line %d: expected KEY=VALUE, got %q", n, line)
+		}
+		k, v := strings.TrimSpace(line[:i]), strings.TrimSpace(line[i+1:])
+		v, err := kvValue(v)
+		if err != nil {
+			return xerrors.Errorf("line %d: %w", n, err)
+		}
+		data[k] = v
+	}
+	return sc.Err()
+}
+
+// kvValue removes quotes or a trailing comment from v.
+func kvValue(v string) (string, error) {
+	if v == "" || (v[0] != '"' && v[0] != '\'') {
+		if i := strings.Index(v, " #"); i >= 0 {
+			v = strings.TrimSpace(v[:i])
+		}
+		return v, nil
+	}
+
+	end := -1
+	for i := 1; i < len(v); i++ {
+		if v[i] == '\\' && v[0] == '"' {
+			i++
+			continue
+		}
+		if v[i] == v[0] {
+			end = i + 1
+			break
+		}
+	}
+	if end < 0 {
+		return "", fmt.Errorf("unterminated quote in %s", v)
+	}
+	if rest := strings.TrimSpace(v[end:]); rest != "" && !strings.HasPrefix(rest, "#") {
+		return "", fmt.Errorf("unexpected %q after quoted value", rest)
+	}
+	if v[0] == '\'' {
+		return v[1 : end-1], nil
+	}
+	return strconv.Unquote(v[:end])
+}
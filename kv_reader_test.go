@@ -0,0 +1,49 @@
+package lookup_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/carloslenz/lookup"
+)
+
+func TestKVReader(t *testing.T) {
+	input := `# Comment
+HOST = localhost
+URL=http://example.com/#anchor # comment
+NAME="my \"server\"" # comment
+RAW='a\nb'
+EMPTY=
+`
+	r := &closeRecorder{Reader: strings.NewReader(input)}
+	l := lookup.NewKVReader(r)
+	tests := []struct {
+		key, val string
+		found    bool
+	}{
+		{"HOST", "localhost", true},
+		{"URL", "http://example.com/#anchor", true},
+		{"NAME", `my "server"`, true},
+		{"RAW", `a\nb`, true},
+		{"EMPTY", "", true},
+		{"OTHER", "", false},
+	}
+	for _, test := range tests {
+		t.Run(test.key, func(t *testing.T) {
+			v, ok, err := l.LookupKey(test.key)
+			if v != test.val || ok != test.found || err != nil {
+				t.Errorf("Unexpected result: got %q/%t/%v, expecting %q/%t", v, ok, err, test.val, test.found)
+			}
+		})
+	}
+	if r.closed != 1 {
+		t.Errorf("Reader closed %d times, expecting once", r.closed)
+	}
+
+	for _, input := range []string{"A=1\nB", "A=1\n=2", `A="1`, `A='1' x`} {
+		_, _, err := lookup.NewKVReader(strings.NewReader(input)).LookupKey("A")
+		if err == nil || !strings.HasPrefix(err.Error(), "line ") {
+			t.Errorf("Unexpected error for %q: %v", input, err)
+		}
+	}
+}
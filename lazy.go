@@ -0,0 +1,89 @@
+package lookup
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// LazyConfig loads fields of a struct on first access, for configurations with expensive
+// lookers of which only a few fields are used. See Bind.
+type LazyConfig struct {
+	options Options
+	r       Reporter
+	seq     []Looker
+
+	mutex  sync.Mutex
+	value  reflect.Value // Pointer to struct.
+	fields map[string]bool
+	loaded map[string]bool
+}
+
+// Bind returns a LazyConfig for the type of prototype, a struct or pointer to struct. Nothing is
+// looked up until Get is called.
+func Bind(prototype interface{}, r Reporter, seq ...Looker) (*LazyConfig, error) {
+	return Options{}.Bind(prototype, r, seq...)
+}
+
+// Bind is like the Bind function, customized by o. Strict and OnlyFields are ignored.
+func (o Options) Bind(prototype interface{}, r Reporter, seq ...Looker) (*LazyConfig, error) {
+	t := reflect.TypeOf(prototype)
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return nil, errors.New("Bind needs a struct or pointer to struct")
+	}
+	o.Strict = false
+	c := &LazyConfig{
+		options: o,
+		r:       r,
+		seq:     seq,
+		value:   reflect.New(t),
+		fields:  make(map[string]bool),
+		loaded:  make(map[string]bool),
+	}
+	for _, d := range o.Describe(prototype) {
+		c.fields[d.Field] = true
+	}
+	return c, nil
+}
+
+// Get returns the value of field, a path like FieldDescriptor.Field (e.g, "DB.Host"), looking it
+// up on the first call with the same tag rules as Lookup. Errors are not cached, so failed fields
+// are looked up again. Defaults can't reference other keys, requiredif only sees fields already
+// loaded and groups of GroupRequirer are not checked. Calls are serialized.
+func (c *LazyConfig) Get(field string) (interface{}, error) {
+	if !c.fields[field] {
+		return nil, fmt.Errorf("unknown field %q", field)
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if !c.loaded[field] {
+		o := c.options
+		o.OnlyFields = []string{field}
+		if err := o.lookup(c.value.Interface(), c.r, c.seq, true); err != nil {
+			return nil, err
+		}
+		c.loaded[field] = true
+	}
+	return fieldByPath(c.value.Elem(), field), nil
+}
+
+// fieldByPath returns the value of the field at path in v, or its zero value if nil pointers
+// are in the way.
+func fieldByPath(v reflect.Value, path string) interface{} {
+	for _, name := range strings.Split(path, ".") {
+		if v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				v = reflect.New(v.Type().Elem())
+			}
+			v = v.Elem()
+		}
+		v = v.FieldByName(name)
+	}
+	return v.Interface()
+}
@@ -0,0 +1,62 @@
+package lookup_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/carloslenz/lookup"
+)
+
+func TestLazyConfig(t *testing.T) {
+	type conf struct {
+		Host string `lookup:"HOST"`
+		Port int    `lookup:"PORT,default=80"`
+		DB   *struct {
+			Name string `lookup:"NAME"`
+		} `lookup:"DB"`
+		Missing string `lookup:"MISSING"`
+	}
+	remote := lookup.NewRecordingMap(lookup.Map{"HOST": "localhost"})
+	var e entries
+	c, err := lookup.Bind(conf{}, &e, remote)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if len(remote.Accessed()) != 0 {
+		t.Errorf("Bind should not look up keys: %v", remote.Accessed())
+	}
+
+	for i := 0; i < 2; i++ {
+		if v, err := c.Get("Host"); v != "localhost" || err != nil {
+			t.Errorf("Unexpected result: got %v/%v, expecting %q", v, err, "localhost")
+		}
+	}
+	if accessed := remote.Accessed(); !reflect.DeepEqual(accessed, []string{"HOST"}) {
+		t.Errorf("Unexpected keys looked up: %v", accessed)
+	}
+	if v, err := c.Get("Port"); v != 80 || err != nil {
+		t.Errorf("Unexpected result: got %v/%v, expecting 80", v, err)
+	}
+	if v, err := c.Get("DB.Name"); v != "" || err != nil {
+		t.Errorf("Unexpected result for missing group: got %#v/%v", v, err)
+	}
+	if _, err := c.Get("Missing"); err == nil {
+		t.Error("Field is required, why no error?!")
+	}
+	if _, err := c.Get("Other"); err == nil {
+		t.Error("Field is unknown, why no error?!")
+	}
+	if expected := (entries{"HOST", "localhost", "PORT", "80"}); !reflect.DeepEqual(e, expected) {
+		t.Errorf("Unexpected reports: %#v, expecting %#v", e, expected)
+	}
+}
+
+func TestLazyConfigGroups(t *testing.T) {
+	c, err := lookup.Bind(groupsConf{}, nil, lookup.Map{"DB_HOST": "h"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if v, err := c.Get("Host"); v != "h" || err != nil {
+		t.Errorf("Unexpected result: got %v/%v, expecting %q", v, err, "h")
+	}
+}
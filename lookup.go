@@ -5,30 +5,119 @@ Instructions
 
 Define "lookup" tags for struct fields. The value should consist of the key to lookup followed by
 ",optional" when the field is not required. There is also compatibility with "encoding/json" tags,
-so you don't need to define both if the keys match.
+so you don't need to define both if the keys match. Like encoding/json, an empty key (e.g,
+",optional") means the field name and "-" skips the field.
+
+A field tagged ",requiredif=OTHER_KEY" is optional unless the field with key OTHER_KEY is not
+its zero value (e.g, a true bool). Fields are processed in declaration order, so OTHER_KEY must
+belong to a field declared before it.
+
+A field tagged ",join=SEP" has a key like "HOST:PORT": each key separated by ":" is looked up and
+the values are joined with SEP. For optional fields, missing keys are empty.
+
+Interface fields are set by a factory registered with RegisterType, named by their value. If the
+factory returns a pointer to struct, it is filled in like a nested struct.
+
+A field tagged ",default=VALUE" gets VALUE when its key is not found, so it is never missing.
+The default takes the rest of the tag, commas included, so it must be the last option. It can
+reference other keys of the struct as ${KEY} (with prefixes, for nested fields), which are
+replaced with their values as found, or as set by their own defaults. References to keys of
+fields declared before are expanded immediately, the others after all fields are processed.
+References to keys that have no value, directly or through a cycle (e.g, A defaults to ${B} and
+B to ${A}), are an error. Reporters implementing SourceReporter are told DefaultSource for values
+set by defaults.
+
+A slice field tagged ",indexed" gets its items from keys followed by consecutive indexes (e.g,
+ITEM_0, ITEM_1) until one is missing. See Options to change the separator and the first index.
+
+A field tagged ",source=NAME" is only looked up in the item of seq named NAME, which is a
+NamedLooker or a source of a Chain (see NewChain). Lookup fails if there is no such item.
+
+A field tagged ",parser=NAME" is set by the function registered with RegisterFieldParser as NAME,
+even if its type is a struct.
+
+A slice, array, map or struct field tagged ",enc=json" is decoded from JSON (e.g, SERVERS=["a","b"]
+or LIMITS={"cpu":2}) instead of being split by commas or filled in recursively.
+
+Requirements on groups of keys, like "either DATABASE_URL or all of DB_HOST, DB_PORT and DB_NAME",
+are declared by the struct implementing GroupRequirer, and checked after all fields are processed.
+
+A field tagged ",deprecated" or ",deprecated=MESSAGE" loads normally, but reporters implementing
+DeprecationReporter are told when its key is found.
+
+Fields that are structs (or pointers to struct) are filled in recursively. When such a field has
+a tag, its key followed by "_" prefixes the keys of nested fields (see Options to change that).
+Nil pointers are allocated only if some nested key is found, so optional groups of fields stay nil
+when absent, even if they contain required fields.
 
 lookup.Lookup() accepts multiple Looker functions like lookup.Env. To adapt existing functions use
 lookup.NoError and lookup.NoBool. To load system configuration files use lookup.NewJSONFile. Typically
-the last step has the defaults in a lookup.Map.
+the last step has the defaults in a lookup.Map. lookup.SaveJSON writes a struct back in a format
+lookup.NewJSONFile loads.
 
 Supported types
 
 Everything fmt.Sscanln supports (because fmt.Sscan does not report an error when bools or floats
-don't consume the string entirely) but newline is inserted internally. This means custom types can
-implement fmt.Scanner. Exceptions:
+don't consume the string entirely) but newline is inserted internally. Exceptions:
 
-	- string: used directly.
-	- []byte: decoded as base64.
+	- integers: parsed by strconv with base 0, so Go literals like 0xFF, 0o755, 0b1010, 0755 and
+	  1_000 are accepted. With ",unit=bytes", sizes with SI or binary suffixes (e.g, "10MB",
+	  "512KiB") are converted to bytes, and ",unit=duration" parses like time.ParseDuration into
+	  nanoseconds.
+	- floats: with ",unit=percent", values ending with "%" are divided by 100 (e.g, "75%" is 0.75),
+	  and other values are plain floats (e.g, "0.1").
+	- time.Time: with ",layout=LAYOUT" (or Options.TimeLayout), parsed by time.Parse (e.g,
+	  ",layout=2006-01-02"), otherwise as RFC 3339 (see json.Unmarshaler below).
+	- time.Duration: parsed by time.ParseDuration (e.g, "1m30s"), but integers are nanoseconds
+	  (e.g, "-1") and Options.DurationKeywords adds words like "unlimited".
+	- os.FileMode: octal (e.g, "644" or "0644") unless prefixed, up to 07777.
+	- string: used directly, also for named types like "type Level string". Likewise, named byte
+	  slices are handled like []byte.
+	- []byte: decoded as base64, unless tagged ",raw" to keep the bytes of the string, or
+	  ",enc=hex". With ",enc=auto", values made only of pairs of hex digits are decoded as hex and
+	  the others as base64. Short values may be valid in both (e.g, "beef"), so prefer explicit
+	  encodings when possible.
+	- bool and *bool: empty values are false, so "-verbose=" (see NewArgs) and "--verbose=false"
+	  disable the flag, while a bare "-verbose" enables it. A *bool is allocated only when the key
+	  is found, so nil means unset.
+	- net.IP, net.IPNet (CIDR notation) and url.URL, or pointers to the last two.
+	- HostPort (or *HostPort): split by net.SplitHostPort, so "0.0.0.0:8080", "[::1]:443" and
+	  ":8080" work, and reported as its String.
+	- *time.Location: loaded with time.LoadLocation (e.g, "America/Sao_Paulo").
+	- slices and arrays: split by ",", or by the ",sep=X" tag option, unless the value is a JSON
+	  array (e.g, from NewJSONFile), whose items are used instead. Arrays require exactly their
+	  length in items. Slices of structs are decoded as JSON arrays and reported as their number
+	  of items.
+	- json.RawMessage: gets JSON from lookers implementing RawJSONLooker (e.g, objects from
+	  NewJSONFile, for parsing later), and from other lookers values that are valid JSON as they
+	  are and other values as JSON strings. Reported as its length.
+	- json.Unmarshaler: receives values that are valid JSON as they are (e.g, objects from
+	  NewJSONFile), and other values, or values it rejects, quoted as JSON strings.
+	- encoding.TextUnmarshaler: receives the string, unless the type is also a json.Unmarshaler
+	  (e.g, time.Time, which is loaded as RFC 3339).
+	- flag.Value: Set receives the string, unless the type is also a json.Unmarshaler or an
+	  encoding.TextUnmarshaler, and String is reported.
+	- fmt.Scanner: reads the whole string, including spaces and newlines, and must consume it.
+	  Used only if the type is neither a json.Unmarshaler, an encoding.TextUnmarshaler nor a
+	  flag.Value.
 */
 package lookup
 
 import (
-	"encoding/base64"
+	"encoding"
+	"encoding/json"
 	"errors"
+	"flag"
 	"fmt"
+	"io"
 	"os"
 	"reflect"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
+
+	"golang.org/x/xerrors"
 )
 
 type (
@@ -43,7 +132,12 @@ type (
 	// NoBool adapts functions that return only value and error to match Looker signature.
 	NoBool struct {
 		F func(string) (string, error)
+		// NotFound, if not nil, tells errors that mean the key was not found (e.g, redis.Nil or
+		// sql.ErrNoRows), which are not returned.
+		NotFound func(error) bool
 	}
+	// LookerFunc adapts ordinary functions to Looker.
+	LookerFunc func(string) (string, bool, error)
 	// Map implements Looker. Use it to store defaults.
 	Map map[string]string
 )
@@ -57,20 +151,93 @@ func (l NoError) LookupKey(s string) (v string, b bool, err error) {
 	return v, b, nil
 }
 
-// LookupKey returns b == True when err == nil.
+// LookupKey returns b == True when err == nil. Errors matched by NotFound are b == false with
+// err == nil.
 func (l NoBool) LookupKey(s string) (v string, b bool, err error) {
 	v, err = l.F(s)
-	return v, err != nil, err
+	if err != nil && l.NotFound != nil && l.NotFound(err) {
+		return "", false, nil
+	}
+	return v, err == nil, err
 }
 
-func lookupKey(s string, l []Looker) (v string, b bool, err error) {
-	for _, e := range l {
+// LookupKey calls f(s).
+func (f LookerFunc) LookupKey(s string) (string, bool, error) {
+	return f(s)
+}
+
+// NopLooker never finds any key. It is handy in tests and as a placeholder in seq.
+var NopLooker Looker = LookerFunc(func(string) (string, bool, error) {
+	return "", false, nil
+})
+
+// NewConst returns a Looker that finds every key with value, e.g. as the last item in seq.
+func NewConst(value string) Looker {
+	return LookerFunc(func(string) (string, bool, error) {
+		return value, true, nil
+	})
+}
+
+// ErrorPolicy tells what Lookup does when an item in seq returns an error.
+type ErrorPolicy int
+
+const (
+	// SkipIfFound, the default, tries the next item and returns the first error only if no later
+	// item finds the key.
+	SkipIfFound ErrorPolicy = iota
+	// FailFast stops the lookup and returns the error.
+	FailFast
+	// SkipOnError treats the error as not found and tries the next item. The error is passed to
+	// reporters implementing ErrorReporter.
+	SkipOnError
+)
+
+type policyLooker struct {
+	Looker
+	policy ErrorPolicy
+}
+
+// OnError returns a Looker that overrides Options.ErrorPolicy for l. It must be the outermost
+// wrapper of l to have effect.
+func OnError(l Looker, policy ErrorPolicy) Looker {
+	return &policyLooker{
+		Looker: l,
+		policy: policy,
+	}
+}
+
+// itemPolicy returns the policy set by OnError for l, or policy.
+func itemPolicy(l Looker, policy ErrorPolicy) ErrorPolicy {
+	if pl, ok := l.(*policyLooker); ok {
+		return pl.policy
+	}
+	return policy
+}
+
+// lookupKey tries the items of l in order until s is found. trace, if not nil, is called for each
+// item tried.
+func lookupKey(s string, l []Looker, policy ErrorPolicy, r Reporter, trace func(i int, found bool, err error)) (v string, b bool, err error) {
+	var first error
+	for i, e := range l {
+		p := itemPolicy(e, policy)
 		v, b, err = e.LookupKey(s)
-		if err == nil && b {
-			break
+		if trace != nil {
+			trace(i, b, err)
+		}
+		switch {
+		case err != nil && p == SkipOnError:
+			reportError(r, s, err)
+		case err != nil && p == FailFast:
+			return "", false, err
+		case err != nil:
+			if first == nil {
+				first = err
+			}
+		case b:
+			return v, true, nil
 		}
 	}
-	return v, b, err
+	return "", false, first
 }
 
 // LookupKey searches s in map.
@@ -79,6 +246,21 @@ func (l Map) LookupKey(s string) (v string, b bool, err error) {
 	return v, b, nil
 }
 
+// Keys returns all keys in map.
+func (l Map) Keys() []string {
+	return mapKeys(l)
+}
+
+func mapKeys(m interface{}) []string {
+	keys := reflect.ValueOf(m).MapKeys()
+	l := make([]string, len(keys))
+	for i, k := range keys {
+		l[i] = k.String()
+	}
+	sort.Strings(l)
+	return l
+}
+
 var lookupTags = []struct {
 	tag, optional string
 }{
@@ -86,100 +268,1051 @@ var lookupTags = []struct {
 	{"lookup", "optional"},
 }
 
+// RawJSONLooker is implemented by lookers that can provide values as JSON, like those for JSON
+// objects do (e.g, NewJSONFile), for json.RawMessage fields. Other lookers give strings, which
+// are kept if they are valid JSON and encoded as JSON strings otherwise.
+type RawJSONLooker interface {
+	LookupRawJSON(string) (json.RawMessage, bool, error)
+}
+
+// rawJSONSeq returns seq with items implementing RawJSONLooker, even wrapped by OnError or
+// NamedLooker, replaced by lookers of their raw values.
+func rawJSONSeq(seq []Looker) []Looker {
+	raw := make([]Looker, len(seq))
+	for i, l := range seq {
+		raw[i] = rawJSON(l)
+	}
+	return raw
+}
+
+func rawJSON(l Looker) Looker {
+	switch l := l.(type) {
+	case *policyLooker:
+		return &policyLooker{Looker: rawJSON(l.Looker), policy: l.policy}
+	case NamedLooker:
+		return NamedLooker{Name: l.Name, Looker: rawJSON(l.Looker)}
+	case RawJSONLooker:
+		return LookerFunc(func(k string) (string, bool, error) {
+			b, ok, err := l.LookupRawJSON(k)
+			return string(b), ok, err
+		})
+	}
+	return l
+}
+
+// Enumerable is implemented by lookers that can list all keys they provide.
+type Enumerable interface {
+	Keys() []string
+}
+
+// FallibleEnumerable can be implemented by Enumerable lookers whose keys come from a source that
+// can fail to load (e.g, NewJSONFile), so the error is not mistaken for a source without keys.
+type FallibleEnumerable interface {
+	KeysErr() ([]string, error)
+}
+
+// ListAll returns the union of keys provided by Enumerable items in seq, sorted.
+// Other items are ignored, as well as items that fail to load their keys.
+func ListAll(seq ...Looker) []string {
+	keys, _ := listAll(seq, SkipOnError, discard)
+	return keys
+}
+
+// listAll is like ListAll, but returns the errors of items that fail to load their keys according
+// to policy.
+func listAll(seq []Looker, policy ErrorPolicy, r Reporter) ([]string, error) {
+	all := make(map[string]bool)
+	for _, l := range seq {
+		keys, err := keysOf(l)
+		if err != nil {
+			if itemPolicy(l, policy) != SkipOnError {
+				return nil, err
+			}
+			reportError(r, "", err)
+		}
+		for _, k := range keys {
+			all[k] = true
+		}
+	}
+	return mapKeys(all), nil
+}
+
+// enumerable returns l as Enumerable, unwrapping OnError.
+func enumerable(l Looker) (Enumerable, bool) {
+	if pl, ok := l.(*policyLooker); ok {
+		l = pl.Looker
+	}
+	en, ok := l.(Enumerable)
+	return en, ok
+}
+
+// keysOf returns the keys of l, or nil if it is not Enumerable, and the error loading them if it
+// implements FallibleEnumerable.
+func keysOf(l Looker) ([]string, error) {
+	en, ok := enumerable(l)
+	if !ok {
+		return nil, nil
+	}
+	if fe, ok := en.(FallibleEnumerable); ok {
+		return fe.KeysErr()
+	}
+	return en.Keys(), nil
+}
+
+// CloseAll flushes the items of things that implement Flusher (e.g, reporters), then closes those
+// that implement io.Closer (e.g, lookers holding files). All items are processed even if some fail,
+// and the first error is returned. Lookup never does it, because lookers and reporters can be
+// reused. Among the built-in types, DotEnvReporter and SnapshotReporter implement Flusher, and
+// DupReporter, FilterSecretsReporter, LeveledReporter and ReportTransformer forward Flush to their
+// reporters.
+func CloseAll(things ...interface{}) error {
+	var first error
+	for _, t := range things {
+		var err error
+		if f, ok := t.(Flusher); ok {
+			err = f.Flush()
+		}
+		if c, ok := t.(io.Closer); ok {
+			if cerr := c.Close(); err == nil {
+				err = cerr
+			}
+		}
+		if err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+// Options customizes Lookup. The zero value has the same behavior as the Lookup function.
+type Options struct {
+	// Strict makes Lookup fail if an Enumerable item in seq provides keys that don't match any
+	// field. Items that are not Enumerable (e.g, Env) are not checked.
+	Strict bool
+	// Provided, if not nil, receives for each field key whether some item in seq provided a
+	// value. This tells zero values that were set explicitly from those that were not found.
+	Provided map[string]bool
+	// AllowDuplicateKeys disables the error when sibling fields have the same key.
+	AllowDuplicateKeys bool
+
+	// Separator joins the prefix taken from a struct field to the keys of its nested fields.
+	// Defaults to DefaultSeparator, so DB.Host is "DB_HOST".
+	Separator string
+	// PrefixFromFieldName takes prefixes from struct field names, even untagged ones, instead of
+	// their keys. Embedded structs are never prefixed.
+	PrefixFromFieldName bool
+	// PrefixCase, if not nil, transforms prefixes (e.g, strings.ToLower).
+	PrefixCase func(string) string
+
+	// ThousandsSep is removed from values of numeric fields (e.g, "," for "1,234.56").
+	ThousandsSep string
+	// DecimalMark is replaced with "." in values of numeric fields (e.g, "," for "1.234,56").
+	DecimalMark string
+
+	// UseFieldNames makes exported fields without tags required fields whose keys are their names,
+	// transformed by FieldNameTransform if not nil (e.g, ToScreamingSnake). Tags always win.
+	UseFieldNames      bool
+	FieldNameTransform func(string) string
+
+	// ErrorPolicy applies to items in seq not wrapped by OnError. Defaults to SkipIfFound.
+	ErrorPolicy ErrorPolicy
+
+	// KeyPrefix is prepended to the keys of struct fields before looking them up in seq (e.g,
+	// "MYAPP_"). Reporters and Provided get keys without it, unless ReportKeyPrefix is set.
+	KeyPrefix       string
+	ReportKeyPrefix bool
+
+	// OnlyFields, if not empty, restricts Lookup to the fields named by it, and SkipFields leaves
+	// out the fields named by it, for loading configuration in phases. Names are paths like
+	// Describe's FieldDescriptor.Field (e.g, "DB.Host"), and naming a struct field includes all
+	// its nested fields. Fields left out are not changed, even if required.
+	OnlyFields []string
+	SkipFields []string
+
+	// IndexSeparator joins keys of fields tagged ",indexed" to indexes starting at IndexStart.
+	// Defaults to DefaultSeparator, so indexes of "ITEM" are looked up as "ITEM_0", "ITEM_1", etc.
+	IndexSeparator string
+	IndexStart     int
+
+	// AllRequired makes all fields required, ignoring ",optional" (or ",omitempty"), ",requiredif="
+	// and optional groups of nested fields, e.g. to check in production that nothing is unset.
+	// Combine it with AllErrors to get all missing fields at once.
+	AllRequired bool
+
+	// AllErrors makes Lookup process all fields when some fail (e.g, parse errors or missing
+	// required fields) and return all errors, as Errors, instead of only the first one.
+	AllErrors bool
+
+	// StrictTags makes Lookup fail for tags with unknown options (e.g, "PORT,optinal"), which are
+	// ignored otherwise. Share an Options value with it set to enable it everywhere.
+	StrictTags bool
+
+	// EnvFallback makes Lookup try keys not found in seq again converted by ToScreamingSnake, so
+	// fields tagged only with `json:"dbHost"` are also found as DB_HOST (e.g, in Env). Reporters
+	// implementing AliasReporter are told when the converted key matches.
+	EnvFallback bool
+
+	// TrimNewline removes a single trailing "\n" or "\r\n" from all values found in seq, before
+	// parsing them, like the TrimNewline Looker does for a single item.
+	TrimNewline bool
+
+	// DetectShadowing makes Lookup keep looking up keys found in the items of seq after the one
+	// that provided them, and tell reporters implementing ShadowReporter about those that have it
+	// too (e.g, PORT set in env but also in args, which wins). It costs extra lookups.
+	DetectShadowing bool
+
+	// Tracer, if not nil, is told about each item of seq tried for each key, found or not.
+	Tracer Tracer
+
+	// ListSeparator, TimeLayout and ByteEncoding are the defaults of the tag options ",sep=",
+	// ",layout=" and ",enc=", for fields without them, so structs that use the same conventions
+	// don't need to repeat them in every tag. Tags always win. TimeLayout is also the way to use
+	// layouts with commas (e.g, time.RFC1123), which can't be in tags. ByteEncoding is one of the
+	// encodings of []byte fields ("base64", "hex" or "auto").
+	ListSeparator string
+	TimeLayout    string
+	ByteEncoding  string
+
+	// DurationKeywords maps words to the values of duration fields (e.g, "unlimited" to
+	// math.MaxInt64 or "none" to 0).
+	DurationKeywords map[string]time.Duration
+
+	// Redact, if not nil, matches keys that Dump and SaveJSON leave out (e.g, a regexp for
+	// secrets), so they are loaded from other items of seq.
+	Redact interface {
+		MatchString(string) bool
+	}
+}
+
+// Tracer receives from Lookup each attempt to find a key of a field in an item of seq, e.g. for
+// debugging why a value came from some source. Unlike Reporter, it sees items that missed or
+// failed too. field is a path like "DB.Host" and source is the name of a NamedLooker item, or its
+// index in seq. A Chain is a single item, named by its source when the key is found.
+type Tracer interface {
+	Trace(field, key, source string, found bool, err error)
+}
+
+// DefaultSeparator is used when Options.Separator is empty.
+const DefaultSeparator = "_"
+
+func (o *Options) nestedPrefix(prefix string, fieldType reflect.StructField, tag fieldTag) string {
+	segment := tag.key
+	if o.PrefixFromFieldName && !fieldType.Anonymous {
+		segment = fieldType.Name
+	}
+	if segment == notFound {
+		return prefix
+	}
+	if o.PrefixCase != nil {
+		segment = o.PrefixCase(segment)
+	}
+	sep := o.Separator
+	if sep == "" {
+		sep = DefaultSeparator
+	}
+	return prefix + segment + sep
+}
+
 // Lookup uses seq to fill in struct fields according to their tags.
-// e should be a pointer to struct with "lookup" tags defined on its fields.
+// e should be a pointer to struct with "lookup" tags defined on its fields. It can also be a
+// *map[string]interface{}, which receives all keys provided by Enumerable items in seq, as strings.
 // For each field, items in seq are tried in sequence and lookup fails only if all of them fail.
-// Can be nil.
+// Can be nil. A pointer to a nil pointer to struct (e.g, &c for c of type *Conf) is allocated.
 func Lookup(e interface{}, r Reporter, seq ...Looker) error {
+	return Options{}.Lookup(e, r, seq...)
+}
+
+// Lookup is like the Lookup function, customized by o.
+func (o Options) Lookup(e interface{}, r Reporter, seq ...Looker) error {
+	return o.lookup(e, r, seq, false)
+}
+
+// lookup implements Lookup. When lazy, for LazyConfig, e is partially loaded, so groups of
+// GroupRequirer are not checked.
+func (o Options) lookup(e interface{}, r Reporter, seq []Looker, lazy bool) error {
 	value := reflect.ValueOf(e)
-	if value.Kind() != reflect.Ptr || value.IsNil() {
-		return errors.New("Lookup needs a pointer argument")
+	switch {
+	case value.Kind() != reflect.Ptr:
+		return fmt.Errorf("Lookup needs a pointer argument, got %T", e)
+	case value.IsNil():
+		return fmt.Errorf("Lookup needs a non-nil pointer, got nil %T: allocate it first or pass its address", e)
 	}
 
 	if r == nil {
 		r = discard
 	}
 
-	value = value.Elem()
+	if m, ok := e.(*map[string]interface{}); ok {
+		return lookupDynamic(m, o.ErrorPolicy, r, seq)
+	}
+
+	// Pointers to nil pointers to struct are allocated.
+	if elem := value.Elem(); elem.Kind() == reflect.Ptr && elem.Type().Elem().Kind() == reflect.Struct {
+		if elem.IsNil() {
+			elem.Set(reflect.New(elem.Type().Elem()))
+		}
+		value = elem
+	}
+	if value.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("Lookup needs a pointer to struct, got %T", e)
+	}
+
+	s := lookupState{
+		Options:  o,
+		r:        r,
+		seq:      seq,
+		known:    make(map[string]bool),
+		resolved: make(map[string]string),
+		found:    make(map[string]bool),
+	}
+	if _, err := s.lookupStruct(value.Elem(), "", "", false); err != nil {
+		return err
+	}
+	if err := s.expandDeferred(); err != nil {
+		return err
+	}
+	if !lazy {
+		if err := s.checkGroups(value.Interface()); err != nil {
+			return err
+		}
+	}
+
+	if o.Strict {
+		if err := s.fail(checkUnknownKeys(s.known, seq, s.ErrorPolicy, s.r)); err != nil {
+			return err
+		}
+	}
+	if len(s.errs) > 0 {
+		return s.errs
+	}
+	return nil
+}
+
+// Validate runs Lookup into a new instance of the type of prototype, a struct or pointer to
+// struct, and returns all errors found, as Errors.
+func Validate(prototype interface{}, seq ...Looker) error {
+	return Options{}.Validate(prototype, seq...)
+}
+
+// Validate is like the Validate function, customized by o. AllErrors is always set.
+func (o Options) Validate(prototype interface{}, seq ...Looker) error {
+	t := reflect.TypeOf(prototype)
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return errors.New("Validate needs a struct or pointer to struct")
+	}
+	o.AllErrors = true
+	return o.Lookup(reflect.New(t).Interface(), nil, seq...)
+}
+
+// lookupDynamic fills in m with all keys provided by Enumerable items in seq, as strings.
+func lookupDynamic(m *map[string]interface{}, policy ErrorPolicy, r Reporter, seq []Looker) error {
+	if *m == nil {
+		*m = make(map[string]interface{})
+	}
+	keys, err := listAll(seq, policy, r)
+	if err != nil {
+		return err
+	}
+	for _, k := range keys {
+		v, ok, err := lookupKey(k, seq, policy, r, nil)
+		if err != nil {
+			return xerrors.Errorf("lookup for key %q failed: %w", k, err)
+		}
+		if ok {
+			(*m)[k] = v
+			r.Report(k, v)
+		}
+	}
+	return nil
+}
+
+type lookupState struct {
+	Options
+	r   Reporter
+	seq []Looker
+
+	// All keys looked up, for Strict.
+	known map[string]bool
+	// Errors collected for AllErrors.
+	errs Errors
+	// Values of keys found or set by defaults, and defaults waiting for them.
+	resolved map[string]string
+	deferred []deferredDefault
+	// Depth of groups being looked up, the keys resolved by defaults in them and the reports
+	// waiting for them, which are replayed after the outermost group.
+	groups        int
+	groupDefaults []string
+	reports       *reportBuffer
+	// Keys found in seq, for GroupRequirer.
+	found map[string]bool
+	// Path of the field being looked up, for Tracer, and the source of its value, which is the
+	// item winner of seq.
+	field  string
+	source string
+	winner int
+}
+
+// lookupStruct fills in the fields of value, whose keys are prefixed and whose names are prefixed
+// by path for OnlyFields and SkipFields. found tells whether any key was found. For groups
+// (structs behind nil pointers) missing required keys are only an error if some key was found.
+func (s *lookupState) lookupStruct(value reflect.Value, prefix, path string, group bool) (found bool, err error) {
 	t := value.Type()
 
+	// Fields already processed, by key, for requiredif and duplicates.
+	processed := make(map[string]reflect.Value)
+	names := make(map[string]string)
+	var missing error
+
 	for i := 0; i < t.NumField(); i++ {
 		field := value.Field(i)
 		fieldType := t.Field(i)
 
-		fieldKey, optional := findTag(fieldType.Tag)
-		if fieldKey == notFound {
+		tag := findTag(fieldType.Tag, fieldType.Name)
+		if err := s.checkTag(fieldType, tag); err != nil {
+			return found, err
+		}
+		tag = s.tagDefaults(tag)
+
+		if tag.nests(fieldType.Type) {
+			if fieldType.PkgPath != "" && !fieldType.Anonymous {
+				continue
+			}
+			ok, err := s.lookupNested(field, s.nestedPrefix(prefix, fieldType, tag), path+fieldType.Name+".", group)
+			found = found || ok
+			if err != nil {
+				return found, err
+			}
 			continue
 		}
 
-		v, ok, err := lookupKey(fieldKey, seq)
+		tagged := tag.key != notFound
+		if tag = s.fieldNameKey(fieldType, tag); tag.key == notFound {
+			continue
+		}
+		if unsupportedKind(fieldType.Type.Kind()) {
+			if !tagged {
+				// Helpers swept in by UseFieldNames.
+				continue
+			}
+			return found, fmt.Errorf("field %q is tagged but its kind %s is not supported", fieldType.Name, fieldType.Type.Kind())
+		}
+
+		optional := tag.optional
+		if tag.requiredIf != "" {
+			dep, ok := processed[tag.requiredIf]
+			if !ok {
+				return found, fmt.Errorf(
+					"field %q is requiredif %q, which is unknown or not declared before it",
+					fieldType.Name, tag.requiredIf)
+			}
+			optional = isZero(dep)
+		}
+		optional = optional && !s.AllRequired
+		if name, ok := names[tag.key]; ok && !s.AllowDuplicateKeys {
+			return found, fmt.Errorf("fields %q and %q have the same key %q", name, fieldType.Name, tag.key)
+		}
+		processed[tag.key] = field
+		names[tag.key] = fieldType.Name
+		if !s.selected(path + fieldType.Name) {
+			s.markKnown(prefix, tag)
+			continue
+		}
+
+		s.field = path + fieldType.Name
+		seq, err := s.sourceSeq(tag.source)
+		if err != nil {
+			return found, xerrors.Errorf("field %q: %w", fieldType.Name, err)
+		}
+		if fieldType.Type == rawMessageType {
+			seq = rawJSONSeq(seq)
+		}
+		var v string
+		var items []string
+		var ok bool
+		switch {
+		case tag.joined:
+			v, ok, err = s.lookupJoined(seq, prefix, tag, optional)
+		case tag.indexed:
+			items, ok, err = s.lookupIndexed(seq, prefix+tag.key)
+			v = strings.Join(items, defaultSep)
+		default:
+			v, ok, err = s.find(seq, prefix+tag.key)
+		}
+		key := prefix + tag.key
+		if s.ReportKeyPrefix {
+			key = s.KeyPrefix + key
+		}
+		if s.Provided != nil && err == nil {
+			s.Provided[key] = ok
+		}
 		switch {
 		case err != nil:
-			return fmt.Errorf("lookup for for field %q failed: %s", fieldType.Name, err)
+			if err = s.fail(xerrors.Errorf("lookup for field %q failed: %w", fieldType.Name, err)); err != nil {
+				return found, err
+			}
 		case ok:
-			if err = setField(field, v, fieldKey, fieldType.Name, r); err != nil {
-				return fmt.Errorf(
-					"value %q for field %q is not %T: %s", v, fieldType.Name, field.Interface(), err)
+			found = true
+			s.resolved[prefix+tag.key] = v
+			s.found[prefix+tag.key] = true
+			if tag.deprecated {
+				reportDeprecated(s.r, key, tag.deprecation)
+			}
+			if tag.source != "" {
+				s.source = tag.source
+			}
+			source := s.source
+			if tag.indexed {
+				err = s.setIndexed(field, fieldType, tag, key, items)
+			} else {
+				err = s.setFound(field, fieldType, tag, prefix, path, key, v)
+			}
+			if err == nil {
+				reportSource(s.r, key, source)
+			}
+			if err = s.fail(err); err != nil {
+				return found, err
+			}
+
+		case tag.hasDefault:
+			d := deferredDefault{field, fieldType, tag, prefix, path, key}
+			if err = s.fail(s.setDefault(d, false)); err != nil {
+				return found, err
 			}
 
 		case !optional:
-			return fmt.Errorf("missing value for required field %q", fieldType.Name)
+			err = fmt.Errorf("missing value for required field %q", fieldType.Name)
+			if !group || s.AllRequired {
+				if err = s.fail(err); err != nil {
+					return found, err
+				}
+				break
+			}
+			if missing == nil {
+				missing = err
+			}
 		default:
-			r.Report(fieldKey, v)
+			reportMissing(s.r, key)
 		}
 	}
+
+	if found {
+		return found, s.fail(missing)
+	}
+	return found, nil
+}
+
+// fail returns err, unless it is collected for AllErrors.
+func (s *lookupState) fail(err error) error {
+	if err == nil || !s.AllErrors {
+		return err
+	}
+	s.errs = append(s.errs, err)
 	return nil
 }
 
-const notFound = ""
+// setFound sets field with v, the value of key.
+func (s *lookupState) setFound(field reflect.Value, fieldType reflect.StructField, tag fieldTag, prefix, path, key, v string) error {
+	if field.Kind() == reflect.Interface {
+		s.r.Report(key, v)
+		err := s.lookupRegistered(field, v, s.nestedPrefix(prefix, fieldType, tag), path+fieldType.Name+".")
+		if err != nil {
+			return xerrors.Errorf("value %q for field %q: %w", v, fieldType.Name, err)
+		}
+		return nil
+	}
+	if err := s.setField(field, v, key, tag, s.r); err != nil {
+		return xerrors.Errorf("value %q for field %q is not %T: %w", v, fieldType.Name, field.Interface(), err)
+	}
+	return nil
+}
 
-func findTag(tag reflect.StructTag) (key string, optional bool) {
-	for _, def := range lookupTags {
-		if s, ok := tag.Lookup(def.tag); ok && s != "" {
-			parts := strings.Split(s, ",")
-			var key string
-			switch len(parts) {
-			case 0:
-				// Default: use field name, not optional.
-			case 1:
-				key = s
-			default:
-				key = parts[0]
-				optional = parts[1] == def.optional
+// fieldNameKey sets the key of untagged fields when UseFieldNames is enabled.
+func (o *Options) fieldNameKey(fieldType reflect.StructField, tag fieldTag) fieldTag {
+	if tag.key != notFound || !o.UseFieldNames || fieldType.PkgPath != "" {
+		return tag
+	}
+	tag.key = fieldType.Name
+	if o.FieldNameTransform != nil {
+		tag.key = o.FieldNameTransform(tag.key)
+	}
+	return tag
+}
+
+// tagDefaults sets the options of tag that are empty to ListSeparator, TimeLayout and ByteEncoding.
+func (o *Options) tagDefaults(tag fieldTag) fieldTag {
+	if tag.sep == "" {
+		tag.sep = o.ListSeparator
+	}
+	if tag.layout == "" {
+		tag.layout = o.TimeLayout
+	}
+	if tag.enc == "" {
+		tag.enc = o.ByteEncoding
+	}
+	return tag
+}
+
+// selected tells whether the field at path is processed according to OnlyFields and SkipFields.
+func (o *Options) selected(path string) bool {
+	return (len(o.OnlyFields) == 0 || matchesPath(o.OnlyFields, path)) && !matchesPath(o.SkipFields, path)
+}
+
+// markKnown records the keys of a field skipped by OnlyFields or SkipFields, so Strict accepts
+// them. Indexed keys are counted in seq without reporting them.
+func (s *lookupState) markKnown(prefix string, tag fieldTag) {
+	var keys []string
+	switch {
+	case tag.joined:
+		for _, k := range strings.Split(tag.key, ":") {
+			keys = append(keys, s.KeyPrefix+prefix+k)
+		}
+	case tag.indexed:
+		sep := s.IndexSeparator
+		if sep == "" {
+			sep = DefaultSeparator
+		}
+		for i := s.IndexStart; ; i++ {
+			k := s.KeyPrefix + prefix + tag.key + sep + strconv.Itoa(i)
+			if _, ok, err := lookupKey(k, s.seq, s.ErrorPolicy, discard, nil); !ok || err != nil {
+				break
+			}
+			keys = append(keys, k)
+		}
+	default:
+		keys = []string{s.KeyPrefix + prefix + tag.key}
+	}
+	for _, k := range keys {
+		s.addKnown(k)
+		if s.EnvFallback {
+			s.known[ToScreamingSnake(k)] = true
+		}
+	}
+}
+
+// addKnown marks k as known for Strict, as well as the top-level key it reads if it is a JSON
+// Pointer.
+func (s *lookupState) addKnown(k string) {
+	s.known[k] = true
+	if root, ok := pointerRoot(k); ok {
+		s.known[root] = true
+	}
+}
+
+// matchesPath tells whether path is one of names, or nested in one of them.
+func matchesPath(names []string, path string) bool {
+	for _, n := range names {
+		if path == n || strings.HasPrefix(path, n+".") {
+			return true
+		}
+	}
+	return false
+}
+
+// find looks up key, prefixed by KeyPrefix, in seq, applying EnvFallback, DetectShadowing and
+// TrimNewline.
+func (s *lookupState) find(seq []Looker, key string) (string, bool, error) {
+	full := s.KeyPrefix + key
+	s.addKnown(full)
+	if s.ReportKeyPrefix {
+		key = full
+	}
+	v, ok, err := s.lookupKey(full, seq)
+	found := full
+	if s.EnvFallback && !ok && err == nil {
+		if alias := ToScreamingSnake(full); alias != full {
+			if v, ok, err = s.lookupKey(alias, seq); ok {
+				s.known[alias] = true
+				found = alias
+				reportAlias(s.r, key, alias)
 			}
-			return key, optional
 		}
 	}
-	return notFound, false
+	if ok && s.DetectShadowing {
+		s.reportShadowed(seq, found, key, v)
+	}
+	if s.TrimNewline {
+		v = trimNewline(v)
+	}
+	return v, ok, err
 }
 
-func setField(field reflect.Value, v, fieldKey, fieldName string, r Reporter) error {
-	val := field.Interface()
-	switch val.(type) {
-	case string:
-		field.SetString(v)
+// reportShadowed looks up k in the items of seq after the one that provided it with value
+// winning, reporting those that also have it as key.
+func (s *lookupState) reportShadowed(seq []Looker, k, key, winning string) {
+	for i := s.winner + 1; i < len(seq); i++ {
+		if v, ok, err := seq[i].LookupKey(k); ok && err == nil {
+			reportShadowed(s.r, key, sourceName(i, seq[i], k, true), v, s.source, winning)
+		}
+	}
+}
 
-	case []byte:
-		b, err := base64.RawStdEncoding.DecodeString(v)
-		if err != nil {
-			return err
+// lookupKey is like the lookupKey function for the current field, telling Tracer about each item
+// of seq tried and recording the source of the value found.
+func (s *lookupState) lookupKey(key string, seq []Looker) (string, bool, error) {
+	return lookupKey(key, seq, s.ErrorPolicy, s.r, func(i int, found bool, err error) {
+		if s.Tracer != nil {
+			s.Tracer.Trace(s.field, key, sourceName(i, seq[i], key, found), found, err)
 		}
-		field.SetBytes(b)
-		r.Report(fieldKey, b)
-		return nil
+		if found {
+			s.source = sourceName(i, seq[i], key, true)
+			s.winner = i
+		}
+	})
+}
 
-	default:
-		if !field.CanAddr() {
-			return fmt.Errorf("field %q of type %T is not addressable", v, fieldName)
+// sourceName returns the name of l, the item i of seq, if it is a NamedLooker, or i. For a Chain
+// where key was found, it is the name of the source that provided it.
+func sourceName(i int, l Looker, key string, found bool) string {
+	if pl, ok := l.(*policyLooker); ok {
+		l = pl.Looker
+	}
+	switch l := l.(type) {
+	case NamedLooker:
+		return l.Name
+	case *Chain:
+		if name, ok := l.Source(key); ok && found {
+			return name
 		}
-		n, err := fmt.Sscanln(v+"\n", field.Addr().Interface())
+	}
+	return strconv.Itoa(i)
+}
+
+// sourceSeq returns the item of seq named source, which is a NamedLooker or a source of a Chain.
+// An empty source means all items.
+func (s *lookupState) sourceSeq(source string) ([]Looker, error) {
+	if source == "" {
+		return s.seq, nil
+	}
+	for _, l := range s.seq {
+		if pl, ok := l.(*policyLooker); ok {
+			l = pl.Looker
+		}
+		switch l := l.(type) {
+		case NamedLooker:
+			if l.Name == source {
+				return []Looker{l.Looker}, nil
+			}
+		case *Chain:
+			for _, nl := range l.sources {
+				if nl.Name == source {
+					return []Looker{nl.Looker}, nil
+				}
+			}
+		}
+	}
+	return nil, fmt.Errorf("source %q is not in seq", source)
+}
+
+// lookupIndexed looks up key followed by IndexSeparator and consecutive indexes from IndexStart
+// (e.g, ITEM_0, ITEM_1), until one is not found.
+func (s *lookupState) lookupIndexed(seq []Looker, key string) ([]string, bool, error) {
+	sep := s.IndexSeparator
+	if sep == "" {
+		sep = DefaultSeparator
+	}
+	var items []string
+	for i := s.IndexStart; ; i++ {
+		v, ok, err := s.find(seq, key+sep+strconv.Itoa(i))
 		if err != nil {
-			return err
+			return nil, false, err
+		}
+		if !ok {
+			return items, len(items) > 0, nil
+		}
+		items = append(items, v)
+	}
+}
+
+// setIndexed sets field, a slice or array, with items found by lookupIndexed.
+func (s *lookupState) setIndexed(field reflect.Value, fieldType reflect.StructField, tag fieldTag, key string, items []string) error {
+	if k := field.Kind(); k != reflect.Slice && k != reflect.Array {
+		return fmt.Errorf("field %q is tagged indexed but is not a slice or array", fieldType.Name)
+	}
+	if err := s.setItems(field, items, tag); err != nil {
+		return xerrors.Errorf("values %q for field %q: %w", items, fieldType.Name, err)
+	}
+	s.r.Report(key, field.Interface())
+	return nil
+}
+
+// lookupJoined looks up each key in tag.key separated by ":" and joins their values. If optional,
+// missing keys are empty, otherwise they are an error.
+func (s *lookupState) lookupJoined(seq []Looker, prefix string, tag fieldTag, optional bool) (string, bool, error) {
+	keys := strings.Split(tag.key, ":")
+	values := make([]string, len(keys))
+	found := false
+	for i, k := range keys {
+		v, ok, err := s.find(seq, prefix+k)
+		switch {
+		case err != nil:
+			return "", false, err
+		case !ok && !optional:
+			return "", false, fmt.Errorf("missing value for key %q", prefix+k)
 		}
-		if n != 1 {
-			return errors.New("nothing to read")
+		found = found || ok
+		values[i] = v
+	}
+	return strings.Join(values, tag.join), found, nil
+}
+
+// lookupNested handles fields that are structs or pointers to struct. Nil pointers are allocated
+// and only kept if some key was found. Defaults are only reported then, and otherwise forgotten.
+func (s *lookupState) lookupNested(field reflect.Value, prefix, path string, group bool) (bool, error) {
+	if field.Kind() != reflect.Ptr {
+		return s.lookupStruct(field, prefix, path, group)
+	}
+	if !field.IsNil() {
+		return s.lookupStruct(field.Elem(), prefix, path, group)
+	}
+	if !field.CanSet() {
+		return false, nil
+	}
+	if s.groups == 0 {
+		// Reports wait until it is known whether the group is kept.
+		r := s.r
+		s.reports = new(reportBuffer)
+		s.r = s.reports
+		defer func() {
+			s.r = r
+			s.reports.replay(r)
+			s.reports = nil
+		}()
+	}
+	reports, defaults, deferred := len(s.reports.calls), len(s.groupDefaults), len(s.deferred)
+
+	v := reflect.New(field.Type().Elem())
+	s.groups++
+	found, err := s.lookupStruct(v.Elem(), prefix, path, true)
+	s.groups--
+	if !found {
+		s.reports.dropDefaults(reports)
+		for _, k := range s.groupDefaults[defaults:] {
+			delete(s.resolved, k)
+		}
+		s.groupDefaults = s.groupDefaults[:defaults]
+		s.deferred = s.deferred[:deferred]
+		return false, err
+	}
+	field.Set(v)
+	return true, err
+}
+
+var (
+	scannerType         = reflect.TypeOf((*fmt.Scanner)(nil)).Elem()
+	jsonUnmarshalerType = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+	flagValueType       = reflect.TypeOf((*flag.Value)(nil)).Elem()
+	rawMessageType      = reflect.TypeOf(json.RawMessage(nil))
+)
+
+// unsupportedKind tells whether fields of kind k can never be loaded.
+func unsupportedKind(k reflect.Kind) bool {
+	return k == reflect.Func || k == reflect.Chan || k == reflect.UnsafePointer
+}
+
+// isNested tells whether fields of type t are handled by recursion instead of setField.
+func isNested(t reflect.Type) bool {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct || valueTypes[t] {
+		return false
+	}
+	pt := reflect.PtrTo(t)
+	return !pt.Implements(scannerType) && !pt.Implements(jsonUnmarshalerType) &&
+		!pt.Implements(textUnmarshalerType) && !pt.Implements(flagValueType)
+}
+
+func checkUnknownKeys(known map[string]bool, seq []Looker, policy ErrorPolicy, r Reporter) error {
+	keys, err := listAll(seq, policy, r)
+	if err != nil {
+		return err
+	}
+	var unknown []string
+	for _, k := range keys {
+		if !known[k] {
+			unknown = append(unknown, k)
 		}
 	}
-	r.Report(fieldKey, field.Interface())
+	if len(unknown) > 0 {
+		return fmt.Errorf("unknown keys: %q", unknown)
+	}
 	return nil
 }
+
+func isZero(v reflect.Value) bool {
+	return reflect.DeepEqual(v.Interface(), reflect.Zero(v.Type()).Interface())
+}
+
+// LookupMap is like Lookup but for a dynamic key list instead of struct fields. Returns the values
+// that were found. Keys in required must be found, they are looked up even if missing from keys.
+func LookupMap(keys, required []string, r Reporter, seq ...Looker) (Map, error) {
+	if r == nil {
+		r = discard
+	}
+
+	isRequired := make(map[string]bool, len(required))
+	for _, k := range required {
+		isRequired[k] = true
+	}
+	all := append([]string{}, keys...)
+	for _, k := range required {
+		if !contains(keys, k) {
+			all = append(all, k)
+		}
+	}
+
+	m := make(Map)
+	for _, k := range all {
+		v, ok, err := lookupKey(k, seq, SkipIfFound, r, nil)
+		switch {
+		case err != nil:
+			return nil, xerrors.Errorf("lookup for key %q failed: %w", k, err)
+		case ok:
+			m[k] = v
+		case isRequired[k]:
+			return nil, fmt.Errorf("missing value for required key %q", k)
+		default:
+			reportMissing(r, k)
+			continue
+		}
+		r.Report(k, v)
+	}
+	return m, nil
+}
+
+func contains(l []string, s string) bool {
+	for _, e := range l {
+		if e == s {
+			return true
+		}
+	}
+	return false
+}
+
+const notFound = ""
+
+type fieldTag struct {
+	key        string
+	optional   bool
+	requiredIf string
+	sep        string
+	raw        bool
+	enc        string
+	joined     bool
+	join       string
+	unit       string
+	layout     string
+	parser     string
+	indexed    bool
+	source     string
+
+	deprecated  bool
+	deprecation string
+
+	hasDefault bool
+	def        string
+
+	// Options not recognized, for StrictTags, and the tag they came from.
+	unknown []string
+	name    string
+}
+
+// tagOptions are the options recognized by findTag, besides optional (or omitempty).
+var tagOptions = []string{
+	"requiredif=", "join=", "sep=", "raw", "enc=", "unit=", "layout=", "parser=", "indexed",
+	"source=", "deprecated", "default=",
+}
+
+// checkTag returns an error for options in tag that findTag didn't recognize (e.g, typos like
+// "optinal"), if StrictTags is set.
+func (o *Options) checkTag(fieldType reflect.StructField, tag fieldTag) error {
+	if !o.StrictTags || len(tag.unknown) == 0 {
+		return nil
+	}
+	optional := "optional"
+	if tag.name == "json" {
+		optional = "omitempty"
+	}
+	return fmt.Errorf("field %q has unknown %s tag options %q, expecting %s or %s",
+		fieldType.Name, tag.name, tag.unknown, optional, strings.Join(tagOptions, ", "))
+}
+
+// nests tells whether fields of type t with tag ft are filled in recursively, which is not the
+// case for tags with ",parser=" or ",enc=json".
+func (ft fieldTag) nests(t reflect.Type) bool {
+	return isNested(t) && ft.parser == "" && ft.enc != "json"
+}
+
+// item returns the options of ft that apply to each item of a slice or array: raw, enc (except
+// json, which is for the whole field), unit and layout.
+func (ft fieldTag) item() fieldTag {
+	item := fieldTag{raw: ft.raw, unit: ft.unit, layout: ft.layout}
+	if ft.enc != "json" {
+		item.enc = ft.enc
+	}
+	return item
+}
+
+// findTag parses the tag of field name. Like encoding/json, an empty key means name and "-"
+// means the field is skipped.
+func findTag(tag reflect.StructTag, name string) fieldTag {
+	for _, def := range lookupTags {
+		if s, ok := tag.Lookup(def.tag); ok && s != "" {
+			if s == "-" {
+				return fieldTag{key: notFound}
+			}
+			ft := fieldTag{name: def.tag}
+			// The default takes the rest of the tag, so it can have commas.
+			if i := strings.Index(s, ",default="); i >= 0 {
+				ft.hasDefault = true
+				ft.def = s[i+len(",default="):]
+				s = s[:i]
+			}
+			parts := strings.Split(s, ",")
+			ft.key = parts[0]
+			if ft.key == "" {
+				ft.key = name
+			}
+			for _, opt := range parts[1:] {
+				switch {
+				case opt == def.optional:
+					ft.optional = true
+				case strings.HasPrefix(opt, "requiredif="):
+					ft.requiredIf = strings.TrimPrefix(opt, "requiredif=")
+				case strings.HasPrefix(opt, "join="):
+					ft.joined = true
+					ft.join = strings.TrimPrefix(opt, "join=")
+				case strings.HasPrefix(opt, "enc="):
+					ft.enc = strings.TrimPrefix(opt, "enc=")
+				case strings.HasPrefix(opt, "parser="):
+					ft.parser = strings.TrimPrefix(opt, "parser=")
+				case strings.HasPrefix(opt, "unit="):
+					ft.unit = strings.TrimPrefix(opt, "unit=")
+				case strings.HasPrefix(opt, "layout="):
+					ft.layout = strings.TrimPrefix(opt, "layout=")
+				case strings.HasPrefix(opt, "source="):
+					ft.source = strings.TrimPrefix(opt, "source=")
+				case opt == "indexed":
+					ft.indexed = true
+				case opt == "raw":
+					ft.raw = true
+				case strings.HasPrefix(opt, "sep="):
+					ft.sep = strings.TrimPrefix(opt, "sep=")
+				case opt == "deprecated" || strings.HasPrefix(opt, "deprecated="):
+					ft.deprecated = true
+					ft.deprecation = strings.TrimPrefix(strings.TrimPrefix(opt, "deprecated"), "=")
+				case opt == "" || (opt == "string" && def.tag == "json"):
+					// Stray commas and the encoding/json option, meaningless here.
+				default:
+					ft.unknown = append(ft.unknown, opt)
+				}
+			}
+			return ft
+		}
+	}
+	return fieldTag{key: notFound}
+}
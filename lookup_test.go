@@ -2,14 +2,21 @@ package lookup_test
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"os"
+	"path/filepath"
 	"reflect"
+	"strings"
 	"testing"
+	"time"
 
 	"net/http"
 
+	"golang.org/x/xerrors"
+
 	"github.com/carloslenz/lookup"
 )
 
@@ -156,3 +163,1127 @@ type entries []string
 func (e *entries) Report(key string, v interface{}) {
 	*e = append(*e, key, fmt.Sprint(v))
 }
+
+func TestLookupMap(t *testing.T) {
+	defaults := lookup.Map{"A": "1", "B": "2"}
+	env := lookup.Map{"B": "3", "D": "4"}
+
+	var e entries
+	m, err := lookup.LookupMap([]string{"A", "B", "C"}, []string{"D"}, &e, env, defaults)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	expected := lookup.Map{"A": "1", "B": "3", "D": "4"}
+	if !reflect.DeepEqual(m, expected) {
+		t.Errorf("Unexpected result: %#v, expecting %#v", m, expected)
+	}
+	expectedReports := entries{"A", "1", "B", "3", "C", "", "D", "4"}
+	if !reflect.DeepEqual(e, expectedReports) {
+		t.Errorf("Unexpected reports: %#v, expecting %#v", e, expectedReports)
+	}
+
+	if _, err := lookup.LookupMap([]string{"A"}, []string{"C"}, nil, env, defaults); err == nil {
+		t.Error("C is required and missing, why no error?!")
+	}
+
+	_, err = lookup.LookupMap([]string{"A"}, nil, nil, lookup.NewJSONFile("testdata/missing.json"))
+	var se *lookup.SourceError
+	if !xerrors.As(err, &se) {
+		t.Errorf("Error should be a SourceError, got %v", err)
+	}
+}
+
+func TestLookupRequiredIf(t *testing.T) {
+	type conf struct {
+		TLSEnabled bool   `lookup:"TLS_ENABLED,optional"`
+		TLSCert    string `lookup:"TLS_CERT,requiredif=TLS_ENABLED"`
+	}
+
+	var c conf
+	if err := lookup.Lookup(&c, nil, lookup.Map{}); err != nil {
+		t.Errorf("TLS is disabled, yet error = %s", err)
+	}
+
+	if err := lookup.Lookup(&c, nil, lookup.Map{"TLS_ENABLED": "true"}); err == nil {
+		t.Error("TLS is enabled without cert, why no error?!")
+	}
+
+	c = conf{}
+	m := lookup.Map{"TLS_ENABLED": "true", "TLS_CERT": "cert.pem"}
+	if err := lookup.Lookup(&c, nil, m); err != nil {
+		t.Errorf("There shouldn't be missing fields, yet error = %s", err)
+	}
+	expected := conf{TLSEnabled: true, TLSCert: "cert.pem"}
+	if c != expected {
+		t.Errorf("Unexpected result: %#v, expecting %#v", c, expected)
+	}
+
+	var bad struct {
+		TLSCert    string `lookup:"TLS_CERT,requiredif=TLS_ENABLED"`
+		TLSEnabled bool   `lookup:"TLS_ENABLED,optional"`
+	}
+	if err := lookup.Lookup(&bad, nil, m); err == nil {
+		t.Error("requiredif references a later field, why no error?!")
+	}
+}
+
+func TestLookupStrict(t *testing.T) {
+	var c struct {
+		Port int `json:"Port"`
+	}
+	m := lookup.Map{"Prot": "8080", "Port": "80"}
+
+	if err := lookup.Lookup(&c, nil, m); err != nil {
+		t.Errorf("Strict is disabled, yet error = %s", err)
+	}
+
+	err := lookup.Options{Strict: true}.Lookup(&c, nil, lookup.Env, m)
+	if err == nil || !strings.Contains(err.Error(), `"Prot"`) {
+		t.Errorf("Unexpected error for unknown key: %v", err)
+	}
+}
+
+func TestListAll(t *testing.T) {
+	args := lookup.NewArgs("-", []string{"-A=1", "-C", "blah"})
+	keys := lookup.ListAll(lookup.Env, args, lookup.Map{"B": "2", "C": "3"})
+	expected := []string{"A", "B", "C"}
+	if !reflect.DeepEqual(keys, expected) {
+		t.Errorf("Unexpected keys: %q, expecting %q", keys, expected)
+	}
+}
+
+func TestLookupProvided(t *testing.T) {
+	var c struct {
+		Replicas int `lookup:"REPLICAS,optional"`
+		Workers  int `lookup:"WORKERS,optional"`
+	}
+	o := lookup.Options{Provided: make(map[string]bool)}
+	if err := o.Lookup(&c, nil, lookup.Map{"REPLICAS": "0"}); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	expected := map[string]bool{"REPLICAS": true, "WORKERS": false}
+	if !reflect.DeepEqual(o.Provided, expected) {
+		t.Errorf("Unexpected Provided: %v, expecting %v", o.Provided, expected)
+	}
+}
+
+func TestLookupNested(t *testing.T) {
+	type DBConfig struct {
+		Host string `lookup:"HOST"`
+		Port int    `lookup:"PORT,optional"`
+	}
+	type Log struct {
+		Level string `lookup:"LOG_LEVEL,optional"`
+	}
+	type conf struct {
+		Log
+		Name string    `lookup:"NAME"`
+		DB   *DBConfig `lookup:"DB"`
+	}
+
+	t.Run("all nested present", func(t *testing.T) {
+		var c conf
+		m := lookup.Map{"NAME": "app", "LOG_LEVEL": "debug", "DB_HOST": "localhost", "DB_PORT": "5432"}
+		if err := lookup.Lookup(&c, nil, m); err != nil {
+			t.Fatalf("There shouldn't be missing fields, yet error = %s", err)
+		}
+		if c.Level != "debug" || c.Name != "app" {
+			t.Errorf("Unexpected result: %#v", c)
+		}
+		expected := DBConfig{Host: "localhost", Port: 5432}
+		if c.DB == nil || *c.DB != expected {
+			t.Errorf("Unexpected DB: %#v, expecting %#v", c.DB, expected)
+		}
+	})
+
+	t.Run("none present", func(t *testing.T) {
+		var c conf
+		if err := lookup.Lookup(&c, nil, lookup.Map{"NAME": "app"}); err != nil {
+			t.Fatalf("There shouldn't be missing fields, yet error = %s", err)
+		}
+		if c.DB != nil {
+			t.Errorf("DB should be nil, got %#v", c.DB)
+		}
+	})
+
+	t.Run("partially present", func(t *testing.T) {
+		var c conf
+		if err := lookup.Lookup(&c, nil, lookup.Map{"NAME": "app", "DB_PORT": "5432"}); err == nil {
+			t.Errorf("DB_HOST is missing, why no error?! conf = %#v", c)
+		}
+	})
+}
+
+type address string
+
+func (a *address) Scan(state fmt.ScanState, verb rune) error {
+	var b bytes.Buffer
+	for {
+		r, _, err := state.ReadRune()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		b.WriteRune(r)
+	}
+	*a = address(b.String())
+	return nil
+}
+
+func TestLookupScannerWithSpaces(t *testing.T) {
+	var c struct {
+		Addr address `lookup:"ADDR"`
+	}
+	const addr = "221B Baker Street, London"
+	if err := lookup.Lookup(&c, nil, lookup.Map{"ADDR": addr}); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if c.Addr != addr {
+		t.Errorf("Unexpected result: got %q, expecting %q", c.Addr, addr)
+	}
+}
+
+func TestLookupBoolPointer(t *testing.T) {
+	type conf struct {
+		Verbose *bool `lookup:"verbose,optional"`
+		Color   *bool `lookup:"color,optional"`
+		Debug   *bool `lookup:"debug,optional"`
+	}
+	var c conf
+	var e entries
+	args := lookup.NewArgs("-", []string{"-verbose", "-color=false"})
+	if err := lookup.Lookup(&c, &e, args); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if c.Verbose == nil || !*c.Verbose {
+		t.Errorf("Verbose should be true, got %v", c.Verbose)
+	}
+	if c.Color == nil || *c.Color {
+		t.Errorf("Color should be false, got %v", c.Color)
+	}
+	if c.Debug != nil {
+		t.Errorf("Debug should be nil, got %v", *c.Debug)
+	}
+	expectedReports := entries{"verbose", "true", "color", "false", "debug", ""}
+	if !reflect.DeepEqual(e, expectedReports) {
+		t.Errorf("Unexpected reports: %#v, expecting %#v", e, expectedReports)
+	}
+}
+
+func TestLookerAdapters(t *testing.T) {
+	errMissing := errors.New("missing")
+	tests := []struct {
+		name string
+		l    lookup.Looker
+	}{
+		{"NoBool", lookup.NoBool{F: func(k string) (string, error) {
+			if k == "A" {
+				return "1", nil
+			}
+			return "", errMissing
+		}}},
+		{"LookerFunc", lookup.LookerFunc(func(k string) (string, bool, error) {
+			if k == "A" {
+				return "1", true, nil
+			}
+			return "", false, errMissing
+		})},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if v, ok, err := test.l.LookupKey("A"); v != "1" || !ok || err != nil {
+				t.Errorf("Unexpected result for A: %q/%t/%v", v, ok, err)
+			}
+			if _, ok, err := test.l.LookupKey("B"); ok || err != errMissing {
+				t.Errorf("Unexpected result for B: %t/%v", ok, err)
+			}
+		})
+	}
+}
+
+func TestNoBoolNotFound(t *testing.T) {
+	errNoRows := errors.New("no rows")
+	errTransport := errors.New("connection refused")
+	l := lookup.NoBool{
+		F: func(k string) (string, error) {
+			switch k {
+			case "A":
+				return "1", nil
+			case "B":
+				return "", xerrors.Errorf("query: %w", errNoRows)
+			}
+			return "", errTransport
+		},
+		NotFound: func(err error) bool { return xerrors.Is(err, errNoRows) },
+	}
+	if v, ok, err := l.LookupKey("A"); v != "1" || !ok || err != nil {
+		t.Errorf("Unexpected result for A: %q/%t/%v", v, ok, err)
+	}
+	if v, ok, err := l.LookupKey("B"); v != "" || ok || err != nil {
+		t.Errorf("Unexpected result for B: %q/%t/%v", v, ok, err)
+	}
+	if _, ok, err := l.LookupKey("C"); ok || err != errTransport {
+		t.Errorf("Unexpected result for C: %t/%v", ok, err)
+	}
+}
+
+func TestLookupDuplicateKeys(t *testing.T) {
+	var c struct {
+		Port      int `lookup:"PORT"`
+		AdminPort int `lookup:"PORT"`
+	}
+	m := lookup.Map{"PORT": "80"}
+	err := lookup.Lookup(&c, nil, m)
+	if err == nil || !strings.Contains(err.Error(), `"Port"`) || !strings.Contains(err.Error(), `"AdminPort"`) {
+		t.Errorf("Unexpected error for duplicate keys: %v", err)
+	}
+
+	if err := (lookup.Options{AllowDuplicateKeys: true}).Lookup(&c, nil, m); err != nil {
+		t.Errorf("Duplicates are allowed, yet error = %s", err)
+	}
+	if c.Port != 80 || c.AdminPort != 80 {
+		t.Errorf("Unexpected result: %#v", c)
+	}
+}
+
+func TestJSONFileOptional(t *testing.T) {
+	const missing = "testdata/missing.json"
+	if _, _, err := lookup.NewJSONFile(missing).LookupKey("A"); err == nil {
+		t.Error("File is missing, why no error?!")
+	}
+
+	v, ok, err := lookup.NewJSONFileOptional(missing).LookupKey("A")
+	if v != "" || ok || err != nil {
+		t.Errorf("Unexpected result for missing optional file: %q/%t/%v", v, ok, err)
+	}
+
+	const invalid = "testdata/invalid.json"
+	os.Mkdir("testdata", 0777)
+	if err := ioutil.WriteFile(invalid, []byte(`{"A": `), 0666); err != nil {
+		t.Fatalf("Cannot write testdata file: %s", err)
+	}
+	defer os.Remove(invalid)
+	if _, _, err := lookup.NewJSONFileOptional(invalid).LookupKey("A"); err == nil {
+		t.Error("File is invalid, why no error?!")
+	}
+}
+
+func TestJSONFileSourceError(t *testing.T) {
+	const invalid = "testdata/invalid_source.json"
+	if err := ioutil.WriteFile(invalid, []byte(`{"A": }`), 0666); err != nil {
+		t.Fatalf("Cannot write testdata file: %s", err)
+	}
+	defer os.Remove(invalid)
+
+	var c struct {
+		A string `lookup:"A"`
+	}
+	l := lookup.NewJSONFile(invalid)
+	for i := 0; i < 2; i++ {
+		err := lookup.Lookup(&c, nil, l)
+		var se *lookup.SourceError
+		if !xerrors.As(err, &se) {
+			t.Fatalf("Error should be a SourceError, got %v", err)
+		}
+		if se.Source != invalid || !strings.HasPrefix(err.Error(), `lookup for field "A" failed: `+invalid+": ") {
+			t.Errorf("Unexpected error: %s", err)
+		}
+	}
+}
+
+func TestJSONCFile(t *testing.T) {
+	const filename = "testdata/config.jsonc"
+	content := `{
+	// Where to listen.
+	"URL": "http://example.com/a//b", /* block comment, "quoted" */
+	"QUOTE": "say \"//hi\"",
+	"LIST": [1, 2, 3,],
+	"EMPTY": "",
+	"PORT": 8080, // trailing comma:
+}
+`
+	if err := ioutil.WriteFile(filename, []byte(content), 0666); err != nil {
+		t.Fatalf("Cannot write testdata file: %s", err)
+	}
+	defer os.Remove(filename)
+
+	var c struct {
+		URL   string `lookup:"URL"`
+		Quote string `lookup:"QUOTE"`
+		List  []int  `lookup:"/LIST/2"`
+		Port  int    `lookup:"PORT"`
+	}
+	if err := lookup.Lookup(&c, nil, lookup.NewJSONCFile(filename)); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if c.URL != "http://example.com/a//b" || c.Quote != `say "//hi"` || !reflect.DeepEqual(c.List, []int{3}) || c.Port != 8080 {
+		t.Errorf("Unexpected result: %#v", c)
+	}
+	if _, _, err := lookup.NewJSONFile(filename).LookupKey("URL"); err == nil {
+		t.Error("NewJSONFile should reject comments, why no error?!")
+	}
+}
+
+func TestLookupNestedPrefix(t *testing.T) {
+	type DB struct {
+		Host string `lookup:"host"`
+	}
+	type conf struct {
+		Database DB `lookup:"DB"`
+	}
+	tests := []struct {
+		name string
+		o    lookup.Options
+		key  string
+	}{
+		{"default", lookup.Options{}, "DB_host"},
+		{"dotted lowercase", lookup.Options{Separator: ".", PrefixCase: strings.ToLower}, "db.host"},
+		{"field name", lookup.Options{Separator: "-", PrefixFromFieldName: true}, "Database-host"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var c conf
+			if err := test.o.Lookup(&c, nil, lookup.Map{test.key: "localhost"}); err != nil {
+				t.Fatalf("Unexpected error: %s", err)
+			}
+			if c.Database.Host != "localhost" {
+				t.Errorf("Unexpected result: %#v", c)
+			}
+		})
+	}
+}
+
+func TestLookupUseFieldNames(t *testing.T) {
+	type conf struct {
+		Port    int
+		DBHost  string
+		Tagged  string `lookup:"OTHER"`
+		private string
+	}
+	m := lookup.Map{"PORT": "80", "DB_HOST": "localhost", "OTHER": "x", "TAGGED": "y", "PRIVATE": "z"}
+
+	var c conf
+	if err := lookup.Lookup(&c, nil, m); err != nil || c != (conf{Tagged: "x"}) {
+		t.Errorf("Untagged fields should be ignored by default: %#v, %v", c, err)
+	}
+
+	o := lookup.Options{UseFieldNames: true, FieldNameTransform: lookup.ToScreamingSnake}
+	if err := o.Lookup(&c, nil, m); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if expected := (conf{Port: 80, DBHost: "localhost", Tagged: "x"}); c != expected {
+		t.Errorf("Unexpected result: %#v, expecting %#v", c, expected)
+	}
+
+	c = conf{}
+	if err := (lookup.Options{UseFieldNames: true}).Lookup(&c, nil, lookup.Map{"Port": "80", "DBHost": "h", "OTHER": "x"}); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if expected := (conf{Port: 80, DBHost: "h", Tagged: "x"}); c != expected {
+		t.Errorf("Unexpected result: %#v, expecting %#v", c, expected)
+	}
+}
+
+func TestLookupDynamic(t *testing.T) {
+	var m map[string]interface{}
+	args := lookup.NewArgs("-", []string{"-A=1"})
+	if err := lookup.Lookup(&m, nil, args, lookup.Env, lookup.Map{"A": "0", "B": "2"}); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	expected := map[string]interface{}{"A": "1", "B": "2"}
+	if !reflect.DeepEqual(m, expected) {
+		t.Errorf("Unexpected result: %#v, expecting %#v", m, expected)
+	}
+}
+
+func TestLookupMalformedEnumerable(t *testing.T) {
+	const invalid = "testdata/truncated.json"
+	if err := ioutil.WriteFile(invalid, []byte(`{"A": "1", `), 0666); err != nil {
+		t.Fatalf("Cannot write testdata file: %s", err)
+	}
+	defer os.Remove(invalid)
+
+	var m map[string]interface{}
+	if err := lookup.Lookup(&m, nil, lookup.NewJSONFile(invalid)); err == nil {
+		t.Errorf("File is malformed, why no error?! Got %#v", m)
+	}
+
+	var c struct {
+		A string `lookup:"A"`
+	}
+	err := lookup.Options{Strict: true}.Lookup(&c, nil, lookup.Map{"A": "1"}, lookup.NewJSONFile(invalid))
+	if err == nil {
+		t.Error("File is malformed, why no error?!")
+	}
+
+	err = lookup.Options{Strict: true}.Lookup(&c, nil, lookup.Map{"A": "1"},
+		lookup.OnError(lookup.NewJSONFile(invalid), lookup.SkipOnError))
+	if err != nil {
+		t.Errorf("Unexpected error with SkipOnError: %s", err)
+	}
+}
+
+func TestLookupWrappedDir(t *testing.T) {
+	root, err := ioutil.TempDir("", "lookup")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+	if err := ioutil.WriteFile(filepath.Join(root, "A"), []byte("1\n"), 0666); err != nil {
+		t.Fatal(err)
+	}
+
+	var timed []string
+	sink := func(name string, d time.Duration) {
+		timed = append(timed, name)
+	}
+	dir := lookup.NewDir(root)
+	wrappers := map[string]lookup.Looker{
+		"NamedLooker":     lookup.NamedLooker{Name: "dir", Looker: dir},
+		"TrimNewline":     lookup.TrimNewline(dir),
+		"NewTimed":        lookup.NewTimed("dir", dir, sink),
+		"OverrideWithEnv": lookup.OverrideWithEnv(dir, nil),
+		"Chain":           lookup.NewChain(lookup.NamedLooker{Name: "dir", Looker: dir}),
+	}
+	for name, l := range wrappers {
+		t.Run(name, func(t *testing.T) {
+			var c struct {
+				A string `lookup:"A"`
+			}
+			if err := (lookup.Options{Strict: true}).Lookup(&c, nil, l); err != nil || c.A != "1" {
+				t.Errorf("Unexpected result: %#v, %v", c, err)
+			}
+			var m map[string]interface{}
+			if err := lookup.Lookup(&m, nil, l); err != nil || len(m) != 0 {
+				t.Errorf("Unexpected result: %#v, %v", m, err)
+			}
+		})
+	}
+	if len(timed) != 1 {
+		t.Errorf("Unexpected timed lookups: got %d, expecting 1", len(timed))
+	}
+}
+
+type skippedErrors struct {
+	entries
+	errs []error
+}
+
+func (e *skippedErrors) ReportError(key string, err error) {
+	e.errs = append(e.errs, err)
+}
+
+func TestLookupErrorPolicy(t *testing.T) {
+	errFlaky := errors.New("flaky")
+	flaky := lookup.LookerFunc(func(string) (string, bool, error) {
+		return "", false, errFlaky
+	})
+	var c struct {
+		Port int `lookup:"PORT"`
+	}
+	defaults := lookup.Map{"PORT": "80"}
+
+	if err := lookup.Lookup(&c, nil, flaky, defaults); err != nil || c.Port != 80 {
+		t.Errorf("SkipIfFound should use defaults: %#v, %v", c, err)
+	}
+	if err := lookup.Lookup(&c, nil, flaky, lookup.Map{}); !xerrors.Is(err, errFlaky) {
+		t.Errorf("SkipIfFound should return the error, got %v", err)
+	}
+	if err := (lookup.Options{ErrorPolicy: lookup.FailFast}).Lookup(&c, nil, flaky, defaults); !xerrors.Is(err, errFlaky) {
+		t.Errorf("FailFast should return the error, got %v", err)
+	}
+	c.Port = 0
+	if err := lookup.Lookup(&c, nil, lookup.NewJSONFile("testdata/missing.json"), defaults); err != nil || c.Port != 80 {
+		t.Errorf("Missing file should be skipped: %#v, %v", c, err)
+	}
+
+	var e skippedErrors
+	if err := (lookup.Options{ErrorPolicy: lookup.SkipOnError}).Lookup(&c, &e, flaky, defaults); err != nil || c.Port != 80 {
+		t.Errorf("SkipOnError should use defaults: %#v, %v", c, err)
+	}
+	if len(e.errs) != 1 || e.errs[0] != errFlaky {
+		t.Errorf("Unexpected skipped errors: %v", e.errs)
+	}
+
+	if err := lookup.Lookup(&c, nil, lookup.OnError(flaky, lookup.SkipOnError), defaults); err != nil {
+		t.Errorf("Wrapped looker should be skipped, yet error = %s", err)
+	}
+	o := lookup.Options{ErrorPolicy: lookup.SkipOnError}
+	if err := o.Lookup(&c, nil, lookup.OnError(flaky, lookup.FailFast), defaults); !xerrors.Is(err, errFlaky) {
+		t.Errorf("Wrapped looker should fail fast, got %v", err)
+	}
+	if err := o.Lookup(&c, nil, flaky); err == nil {
+		t.Error("PORT is missing from all sources, why no error?!")
+	}
+
+	wrapped := lookup.OnError(lookup.Map{"PORT": "80", "TYPO": "1"}, lookup.SkipOnError)
+	if err := (lookup.Options{Strict: true}).Lookup(&c, nil, wrapped); err == nil {
+		t.Error("TYPO is unknown, why no error?!")
+	}
+	if keys := lookup.ListAll(wrapped); !reflect.DeepEqual(keys, []string{"PORT", "TYPO"}) {
+		t.Errorf("Unexpected keys of wrapped looker: %q", keys)
+	}
+}
+
+func TestLookupJoin(t *testing.T) {
+	var c struct {
+		Addr string `lookup:"DB_HOST:DB_PORT,join=:"`
+		Opts string `lookup:"OPT_A:OPT_B,optional,join=&"`
+	}
+	var e entries
+	if err := lookup.Lookup(&c, &e, lookup.Map{"DB_HOST": "localhost", "DB_PORT": "5432", "OPT_B": "b=1"}); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if c.Addr != "localhost:5432" || c.Opts != "&b=1" {
+		t.Errorf("Unexpected result: %#v", c)
+	}
+	expectedReports := entries{"DB_HOST:DB_PORT", "localhost:5432", "OPT_A:OPT_B", "&b=1"}
+	if !reflect.DeepEqual(e, expectedReports) {
+		t.Errorf("Unexpected reports: %#v, expecting %#v", e, expectedReports)
+	}
+
+	err := lookup.Lookup(&c, nil, lookup.Map{"DB_HOST": "localhost"})
+	if err == nil || !strings.Contains(err.Error(), `"DB_PORT"`) {
+		t.Errorf("Error should name DB_PORT, got %v", err)
+	}
+}
+
+func TestLookupTagFieldName(t *testing.T) {
+	var c struct {
+		Port    int    `json:",omitempty"`
+		Host    string `lookup:",optional"`
+		Skipped string `json:"-"`
+	}
+	var e entries
+	if err := lookup.Lookup(&c, &e, lookup.Map{"Port": "80", "-": "x", "Skipped": "y"}); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if c.Port != 80 || c.Skipped != "" {
+		t.Errorf("Unexpected result: %#v", c)
+	}
+	if expected := (entries{"Port", "80", "Host", ""}); !reflect.DeepEqual(e, expected) {
+		t.Errorf("Unexpected reports: %#v, expecting %#v", e, expected)
+	}
+}
+
+func TestLookupKeyPrefix(t *testing.T) {
+	var c struct {
+		Host string `json:"HOST"`
+		Port int    `lookup:"PORT,optional"`
+		Addr string `lookup:"HOST:PORT,join=:"`
+		DB   struct {
+			Name string `lookup:"NAME"`
+		} `lookup:"DB"`
+	}
+	m := lookup.Map{"MYAPP_HOST": "localhost", "MYAPP_PORT": "80", "MYAPP_DB_NAME": "app", "HOST": "other"}
+	var e entries
+	o := lookup.Options{KeyPrefix: "MYAPP_"}
+	if err := o.Lookup(&c, nil, lookup.Map{"HOST": "localhost", "DB_NAME": "app"}); err == nil {
+		t.Errorf("Lookup should ignore keys without prefix, why no error?!")
+	}
+	if err := o.Lookup(&c, &e, m); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if c.Host != "localhost" || c.Port != 80 || c.Addr != "localhost:80" || c.DB.Name != "app" {
+		t.Errorf("Unexpected result: %#v", c)
+	}
+	expected := entries{"HOST", "localhost", "PORT", "80", "HOST:PORT", "localhost:80", "DB_NAME", "app"}
+	if !reflect.DeepEqual(e, expected) {
+		t.Errorf("Unexpected reports: %#v, expecting %#v", e, expected)
+	}
+
+	e = nil
+	o.ReportKeyPrefix = true
+	if err := o.Lookup(&c, &e, m); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if e[0] != "MYAPP_HOST" {
+		t.Errorf("Unexpected report key: got %q, expecting %q", e[0], "MYAPP_HOST")
+	}
+}
+
+func TestRecordingMap(t *testing.T) {
+	var c struct {
+		A string `lookup:"A"`
+		B string `lookup:"B,optional"`
+	}
+	m := lookup.NewRecordingMap(lookup.Map{"A": "1"})
+	defaults := lookup.NewRecordingMap(lookup.Map{"B": "2"})
+	if err := lookup.Lookup(&c, nil, m, defaults); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if a := m.Accessed(); !reflect.DeepEqual(a, []string{"A", "B"}) {
+		t.Errorf("Unexpected accessed keys: %q", a)
+	}
+	if a := defaults.Accessed(); !reflect.DeepEqual(a, []string{"B"}) {
+		t.Errorf("Unexpected accessed keys in defaults: %q", a)
+	}
+}
+
+type closer struct {
+	closed bool
+	err    error
+}
+
+func (c *closer) Close() error {
+	c.closed = true
+	return c.err
+}
+
+func TestCloseAll(t *testing.T) {
+	var b bytes.Buffer
+	dotEnv := lookup.NewDotEnvReporter(&b)
+	dotEnv.Report("A", "1")
+	failing := &closer{err: errors.New("close failed")}
+	other := &closer{}
+
+	err := lookup.CloseAll(lookup.DupReporter{dotEnv}, failing, lookup.Map{}, other)
+	if err != failing.err {
+		t.Errorf("Unexpected error: got %v, expecting %v", err, failing.err)
+	}
+	if !failing.closed || !other.closed {
+		t.Errorf("All closers should be closed: %t, %t", failing.closed, other.closed)
+	}
+	if s := b.String(); s != "A=1\n" {
+		t.Errorf("Unexpected output: got %q, expecting %q", s, "A=1\n")
+	}
+}
+
+func TestLookupOnlyFields(t *testing.T) {
+	type conf struct {
+		Log struct {
+			Level string `lookup:"LEVEL"`
+		} `lookup:"LOG"`
+		Debug bool   `lookup:"DEBUG,optional"`
+		DB    string `lookup:"DB,requiredif=DEBUG"`
+		Port  int    `lookup:"PORT"`
+	}
+	var c conf
+	o := lookup.Options{OnlyFields: []string{"Log", "Debug"}}
+	if err := o.Lookup(&c, nil, lookup.Map{"LOG_LEVEL": "info", "DEBUG": "true", "PORT": "80"}); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if c.Log.Level != "info" || !c.Debug || c.Port != 0 {
+		t.Errorf("Unexpected result: %#v", c)
+	}
+
+	o = lookup.Options{SkipFields: []string{"Log.Level", "Debug"}}
+	err := o.Lookup(&c, nil, lookup.Map{"DB": "postgres", "PORT": "80"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if c.Log.Level != "info" || c.DB != "postgres" || c.Port != 80 {
+		t.Errorf("Unexpected result: %#v", c)
+	}
+
+	o = lookup.Options{SkipFields: []string{"Log"}}
+	if err := o.Lookup(&c, nil, lookup.Map{"LOG_LEVEL": "info"}); err == nil {
+		t.Error("DB is required because DEBUG is set, why no error?!")
+	}
+
+	var phased struct {
+		A     string   `lookup:"A"`
+		B     string   `lookup:"B"`
+		Addr  string   `lookup:"HOST:PORT,join=:"`
+		Items []string `lookup:"ITEM,indexed"`
+	}
+	o = lookup.Options{Strict: true, OnlyFields: []string{"A"}}
+	m := lookup.Map{"A": "1", "B": "2", "HOST": "h", "PORT": "80", "ITEM_0": "x", "ITEM_1": "y"}
+	if err := o.Lookup(&phased, nil, m); err != nil {
+		t.Errorf("Keys of skipped fields should be known: %s", err)
+	}
+	m["TYPO"] = "1"
+	if err := o.Lookup(&phased, nil, m); err == nil || !strings.Contains(err.Error(), `["TYPO"]`) {
+		t.Errorf("Unexpected error: got %v, expecting unknown TYPO", err)
+	}
+}
+
+func TestLookupDefault(t *testing.T) {
+	type conf struct {
+		BindAddr string   `lookup:"BIND_ADDR,default=${HOST}:${PORT}"`
+		Host     string   `lookup:"HOST,default=localhost"`
+		Port     int      `lookup:"PORT,default=80"`
+		Hosts    []string `lookup:"HOSTS,default=${HOST},backup"`
+		DB       struct {
+			URL  string `lookup:"URL,default=postgres://${HOST}/${DB_NAME}"`
+			Name string `lookup:"NAME"`
+		} `lookup:"DB"`
+	}
+	var c conf
+	var e entries
+	provided := make(map[string]bool)
+	o := lookup.Options{Provided: provided}
+	if err := o.Lookup(&c, &e, lookup.Map{"PORT": "8080", "DB_NAME": "app"}); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if c.BindAddr != "localhost:8080" || c.Host != "localhost" || c.Port != 8080 ||
+		!reflect.DeepEqual(c.Hosts, []string{"localhost", "backup"}) || c.DB.URL != "postgres://localhost/app" {
+		t.Errorf("Unexpected result: %#v", c)
+	}
+	if provided["HOST"] || !provided["PORT"] {
+		t.Errorf("Defaults should not count as provided: %v", provided)
+	}
+	expected := entries{"HOST", "localhost", "PORT", "8080", "HOSTS", "[localhost backup]",
+		"DB_NAME", "app", "BIND_ADDR", "localhost:8080", "DB_URL", "postgres://localhost/app"}
+	if !reflect.DeepEqual(e, expected) {
+		t.Errorf("Unexpected reports: %#v, expecting %#v", e, expected)
+	}
+
+	type db struct {
+		Port int    `lookup:"PORT,default=5432"`
+		Host string `lookup:"HOST"`
+		URL  string `lookup:"URL,default=${DB_HOST}:${DB_PORT}"`
+	}
+	var group struct {
+		DB   *db    `lookup:"DB"`
+		Addr string `lookup:"ADDR,default=${DB_PORT}"`
+	}
+	e = nil
+	err := lookup.Lookup(&group, &e, lookup.Map{"ADDR": "a"})
+	if err != nil || group.DB != nil {
+		t.Fatalf("Unexpected result: %#v, %v", group, err)
+	}
+	if expected := (entries{"ADDR", "a"}); !reflect.DeepEqual(e, expected) {
+		t.Errorf("Defaults of absent group should not be reported: %#v, expecting %#v", e, expected)
+	}
+	if err := lookup.Lookup(&group, nil, lookup.Map{}); err == nil {
+		t.Error("DB is absent, so ADDR should not get its default, why no error?!")
+	}
+	e = nil
+	if err := lookup.Lookup(&group, &e, lookup.Map{"DB_HOST": "h"}); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if group.DB == nil || group.DB.Port != 5432 || group.DB.URL != "h:5432" || group.Addr != "5432" {
+		t.Errorf("Unexpected result: %#v", group)
+	}
+	expected = entries{"DB_PORT", "5432", "DB_HOST", "h", "DB_URL", "h:5432", "ADDR", "5432"}
+	if !reflect.DeepEqual(e, expected) {
+		t.Errorf("Unexpected reports: %#v, expecting %#v", e, expected)
+	}
+
+	var cycle struct {
+		A string `lookup:"A,default=${B}"`
+		B string `lookup:"B,default=${A}"`
+	}
+	err = lookup.Lookup(&cycle, nil, lookup.Map{})
+	if err == nil || !strings.Contains(err.Error(), `"A"`) {
+		t.Errorf("Unexpected error for cycle: %v", err)
+	}
+	var unresolved struct {
+		A string `lookup:"A,default=${MISSING}"`
+	}
+	if err := lookup.Lookup(&unresolved, nil, lookup.Map{}); err == nil {
+		t.Error("Default references a missing key, why no error?!")
+	}
+}
+
+func TestValidate(t *testing.T) {
+	type conf struct {
+		Host string `lookup:"HOST"`
+		Port int    `lookup:"PORT"`
+		Addr string `lookup:"ADDR,default=${MISSING}"`
+		DB   *struct {
+			Name string `lookup:"NAME"`
+			User string `lookup:"USER"`
+		} `lookup:"DB"`
+	}
+	err := lookup.Validate(conf{}, lookup.Map{"PORT": "eighty", "DB_USER": "admin"})
+	errs, ok := err.(lookup.Errors)
+	if !ok || len(errs) != 4 {
+		t.Fatalf("Unexpected errors: %v", err)
+	}
+	for i, name := range []string{`"Host"`, `"Port"`, `"Name"`, `"Addr"`} {
+		if !strings.Contains(errs[i].Error(), name) {
+			t.Errorf("Error %d should name field %s: %s", i, name, errs[i])
+		}
+	}
+
+	if err := lookup.Validate(&conf{}, lookup.Map{"HOST": "h", "PORT": "80", "ADDR": "a"}); err != nil {
+		t.Errorf("Unexpected error: %s", err)
+	}
+	if err := lookup.Validate(1); err == nil {
+		t.Error("Prototype is not a struct, why no error?!")
+	}
+}
+
+func TestNopAndConstLookers(t *testing.T) {
+	var c struct {
+		Host string `lookup:"HOST"`
+		Port string `lookup:"PORT,optional"`
+	}
+	if err := lookup.Lookup(&c, nil, lookup.NopLooker); err == nil {
+		t.Error("NopLooker finds nothing, why no error?!")
+	}
+
+	seq := []lookup.Looker{lookup.NopLooker, lookup.Map{"HOST": "localhost"}, lookup.NewConst("unset")}
+	if err := lookup.Lookup(&c, nil, seq...); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if c.Host != "localhost" || c.Port != "unset" {
+		t.Errorf("Unexpected result: %#v", c)
+	}
+}
+
+func TestLookupPointers(t *testing.T) {
+	type conf struct {
+		A string `lookup:"A"`
+	}
+	m := lookup.Map{"A": "1"}
+
+	var c *conf
+	err := lookup.Lookup(c, nil, m)
+	if err == nil || !strings.Contains(err.Error(), "non-nil pointer") {
+		t.Errorf("Unexpected error for nil pointer: %v", err)
+	}
+	if err := lookup.Lookup(&c, nil, m); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if c == nil || c.A != "1" {
+		t.Errorf("Unexpected result: %#v", c)
+	}
+
+	err = lookup.Lookup(conf{}, nil, m)
+	if err == nil || !strings.Contains(err.Error(), "needs a pointer argument") {
+		t.Errorf("Unexpected error for struct: %v", err)
+	}
+	var n int
+	err = lookup.Lookup(&n, nil, m)
+	if err == nil || !strings.Contains(err.Error(), "pointer to struct") {
+		t.Errorf("Unexpected error for *int: %v", err)
+	}
+}
+
+type groupsConf struct {
+	URL  string `lookup:"DATABASE_URL,optional"`
+	Host string `lookup:"DB_HOST,optional"`
+	Port int    `lookup:"DB_PORT,optional"`
+	Name string `lookup:"DB_NAME,optional"`
+}
+
+func (groupsConf) RequiredGroups() []lookup.KeyGroup {
+	return []lookup.KeyGroup{
+		{Name: "database", AnyOf: []string{"DATABASE_URL", "DB_HOST"}},
+		{Name: "db", AllOf: []string{"DB_HOST", "DB_PORT", "DB_NAME"}},
+	}
+}
+
+func TestLookupRequiredGroups(t *testing.T) {
+	tests := []struct {
+		m   lookup.Map
+		err string
+	}{
+		{lookup.Map{"DATABASE_URL": "postgres://db"}, ""},
+		{lookup.Map{"DB_HOST": "db", "DB_PORT": "5432", "DB_NAME": "app"}, ""},
+		{lookup.Map{}, `required group "database": missing values for all of DATABASE_URL, DB_HOST`},
+		{lookup.Map{"DB_HOST": "db", "DB_PORT": "5432"}, `required group "db": missing values for DB_NAME, which are required with DB_HOST, DB_PORT`},
+	}
+	for _, test := range tests {
+		var c groupsConf
+		err := lookup.Lookup(&c, nil, test.m)
+		if got := fmt.Sprint(err); (err != nil || test.err != "") && got != test.err {
+			t.Errorf("Unexpected error for %v: got %q, expecting %q", test.m, got, test.err)
+		}
+	}
+
+	err := lookup.Validate(groupsConf{}, lookup.Map{"DB_NAME": "app"})
+	if errs, ok := err.(lookup.Errors); !ok || len(errs) != 2 {
+		t.Errorf("Unexpected errors: %v", err)
+	}
+}
+
+type aliases []string
+
+func (a *aliases) Report(key string, e interface{}) {}
+
+func (a *aliases) ReportAlias(key, alias string) {
+	*a = append(*a, key, alias)
+}
+
+func TestLookupEnvFallback(t *testing.T) {
+	type conf struct {
+		DBHost string `json:"dbHost"`
+		DBPort int    `json:"dbPort"`
+	}
+	env := lookup.Map{"DB_HOST": "localhost"}
+	file := lookup.Map{"dbPort": "5432"}
+
+	var c conf
+	if err := lookup.Lookup(&c, nil, env, file); err == nil {
+		t.Error("DB_HOST is not dbHost, why no error?!")
+	}
+
+	var a aliases
+	r := lookup.DupReporter{&a}
+	if err := (lookup.Options{EnvFallback: true}).Lookup(&c, r, env, file); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if c.DBHost != "localhost" || c.DBPort != 5432 {
+		t.Errorf("Unexpected result: %#v", c)
+	}
+	if expected := (aliases{"dbHost", "DB_HOST"}); !reflect.DeepEqual(a, expected) {
+		t.Errorf("Unexpected aliases: %q, expecting %q", a, expected)
+	}
+
+	if err := (lookup.Options{EnvFallback: true, Strict: true}).Lookup(&c, nil, env, file); err != nil {
+		t.Errorf("Aliases found should be known: %s", err)
+	}
+}
+
+func TestLookupStrictTags(t *testing.T) {
+	var c struct {
+		Host string `lookup:"HOST,optinal"`
+		Port int    `json:"port,ommitempty,string"`
+	}
+	m := lookup.Map{"HOST": "localhost", "port": "80"}
+	if err := lookup.Lookup(&c, nil, m); err != nil {
+		t.Errorf("Unknown options are ignored by default: %s", err)
+	}
+
+	err := (lookup.Options{StrictTags: true}).Lookup(&c, nil, m)
+	expected := `field "Host" has unknown lookup tag options ["optinal"], expecting optional or requiredif=`
+	if err == nil || !strings.HasPrefix(err.Error(), expected) {
+		t.Errorf("Unexpected error: got %v, expecting %q...", err, expected)
+	}
+
+	var d struct {
+		Port int `json:"port,ommitempty,string"`
+	}
+	err = (lookup.Options{StrictTags: true}).Lookup(&d, nil, m)
+	if err == nil || !strings.Contains(err.Error(), `["ommitempty"], expecting omitempty or`) {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
+
+func TestLookupUnsupportedKinds(t *testing.T) {
+	var c struct {
+		Host     string
+		Callback func()
+		Events   chan string
+	}
+	o := lookup.Options{UseFieldNames: true}
+	if err := o.Lookup(&c, nil, lookup.Map{"Host": "localhost"}); err != nil || c.Host != "localhost" {
+		t.Errorf("Untagged func and chan fields should be skipped: %#v/%v", c, err)
+	}
+
+	var d struct {
+		Callback func() `lookup:"CALLBACK,optional"`
+	}
+	err := lookup.Lookup(&d, nil, lookup.Map{"CALLBACK": "x"})
+	expected := `field "Callback" is tagged but its kind func is not supported`
+	if err == nil || err.Error() != expected {
+		t.Errorf("Unexpected error: got %v, expecting %q", err, expected)
+	}
+}
+
+func TestJSONFiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "lookup")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	base := filepath.Join(dir, "base.json")
+	prod := filepath.Join(dir, "prod.json")
+	files := map[string]string{
+		base: `{"db": {"host": "localhost", "port": 5432}, "servers": ["a", "b"], "debug": true}`,
+		prod: `{"db": {"host": "db.example.com"}, "servers": ["c"]}`,
+	}
+	for name, contents := range files {
+		if err := ioutil.WriteFile(name, []byte(contents), 0666); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	l := lookup.NewJSONFiles(base, prod, filepath.Join(dir, "missing.json"))
+	for k, expected := range map[string]string{
+		"/db/host":   "db.example.com",
+		"/db/port":   "5432",
+		"servers":    `["c"]`,
+		"debug":      "true",
+		"/servers/1": "",
+	} {
+		if v, _, err := l.LookupKey(k); v != expected || err != nil {
+			t.Errorf("Unexpected value for %q: got %q/%v, expecting %q", k, v, err, expected)
+		}
+	}
+
+	if _, _, err := lookup.NewJSONFiles(filepath.Join(dir, "missing.json"), base).LookupKey("debug"); err == nil {
+		t.Error("First file is missing, why no error?!")
+	}
+
+	null := filepath.Join(dir, "null.json")
+	if err := ioutil.WriteFile(null, []byte("null"), 0666); err != nil {
+		t.Fatal(err)
+	}
+	if v, _, err := lookup.NewJSONFiles(null, prod).LookupKey("/db/host"); v != "db.example.com" || err != nil {
+		t.Errorf("Unexpected value over null file: got %q/%v", v, err)
+	}
+}
+
+func TestLookupAllRequired(t *testing.T) {
+	type conf struct {
+		Host  string `lookup:"HOST"`
+		Port  int    `lookup:"PORT,optional"`
+		Debug bool   `json:"debug,omitempty"`
+		TLS   *struct {
+			Cert string `lookup:"CERT"`
+		} `lookup:"TLS"`
+	}
+	m := lookup.Map{"HOST": "localhost"}
+
+	var c conf
+	if err := lookup.Lookup(&c, nil, m); err != nil {
+		t.Errorf("Unexpected error: %s", err)
+	}
+
+	err := (lookup.Options{AllRequired: true, AllErrors: true}).Lookup(&c, nil, m)
+	errs, ok := err.(lookup.Errors)
+	if !ok || len(errs) != 3 {
+		t.Fatalf("Unexpected errors: %v", err)
+	}
+	for i, name := range []string{`"Port"`, `"Debug"`, `"Cert"`} {
+		if !strings.Contains(errs[i].Error(), name) {
+			t.Errorf("Error %d should name field %s: %s", i, name, errs[i])
+		}
+	}
+}
+
+type traces []string
+
+func (tr *traces) Trace(field, key, source string, found bool, err error) {
+	*tr = append(*tr, fmt.Sprintf("%s %s %s %t %v", field, key, source, found, err))
+}
+
+func TestLookupTracer(t *testing.T) {
+	var c struct {
+		Host string `lookup:"HOST"`
+		DB   struct {
+			Port int `lookup:"PORT,optional"`
+		} `lookup:"DB"`
+	}
+	fail := lookup.LookerFunc(func(string) (string, bool, error) {
+		return "", false, errors.New("unavailable")
+	})
+	seq := []lookup.Looker{
+		lookup.OnError(lookup.NamedLooker{Name: "remote", Looker: fail}, lookup.SkipOnError),
+		lookup.NamedLooker{Name: "env", Looker: lookup.Map{}},
+		lookup.Map{"HOST": "localhost"},
+	}
+	var tr traces
+	if err := (lookup.Options{Tracer: &tr}).Lookup(&c, nil, seq...); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	expected := traces{
+		"Host HOST remote false unavailable",
+		"Host HOST env false <nil>",
+		"Host HOST 2 true <nil>",
+		"DB.Port DB_PORT remote false unavailable",
+		"DB.Port DB_PORT env false <nil>",
+		"DB.Port DB_PORT 2 false <nil>",
+	}
+	if !reflect.DeepEqual(tr, expected) {
+		t.Errorf("Unexpected traces: %q, expecting %q", tr, expected)
+	}
+}
@@ -0,0 +1,203 @@
+package lookup
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf16"
+
+	"golang.org/x/xerrors"
+)
+
+type propertiesLooker struct {
+	filename string
+
+	mutex sync.Mutex
+	data  Map
+	err   error
+}
+
+// NewProperties returns a Looker for a Java .properties file, so it can be shared with Java
+// programs. Keys are separated from values by "=", ":" or spaces, lines ending with "\" continue
+// on the next one, "#" and "!" start comments, and escapes like "\n" and "\u00e9" are decoded.
+// The file is read as UTF-8 (not ISO 8859-1, like Java before version 9) and loaded only once.
+func NewProperties(filename string) Looker {
+	return &propertiesLooker{
+		filename: filename,
+	}
+}
+
+func (l *propertiesLooker) LookupKey(k string) (string, bool, error) {
+	if err := l.once(); err != nil {
+		return "", false, err
+	}
+	return l.data.LookupKey(k)
+}
+
+// Keys returns the keys in the file, which is loaded if needed.
+func (l *propertiesLooker) Keys() []string {
+	l.once()
+	return l.data.Keys()
+}
+
+// KeysErr is like Keys, but also returns the error loading the file.
+func (l *propertiesLooker) KeysErr() ([]string, error) {
+	if err := l.once(); err != nil {
+		return nil, err
+	}
+	return l.data.Keys(), nil
+}
+
+func (l *propertiesLooker) once() error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if l.data == nil {
+		// If file fails to load, don't try again for the same instance:
+		l.data = make(Map)
+		if err := l.load(); err != nil {
+			l.err = &SourceError{Source: l.filename, Err: err}
+		}
+	}
+	return l.err
+}
+
+func (l *propertiesLooker) load() error {
+	f, err := os.Open(l.filename)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return parseProperties(f, l.data)
+}
+
+// parseProperties reads the logical lines of r, joining continuations, into data.
+func parseProperties(r io.Reader, data Map) error {
+	sc := bufio.NewScanner(r)
+	var line string
+	start := 0
+	for n := 1; sc.Scan(); n++ {
+		s := sc.Text()
+		if line == "" {
+			s = strings.TrimLeft(s, " \t\f")
+			if s == "" || s[0] == '#' || s[0] == '!' {
+				continue
+			}
+			start = n
+		} else {
+			s = strings.TrimLeft(s, " \t\f")
+		}
+		if continues(s) {
+			line += s[:len(s)-1]
+			continue
+		}
+		line += s
+		if err := parseProperty(line, data); err != nil {
+			return xerrors.Errorf("line %d: %w", start, err)
+		}
+		line = ""
+	}
+	if err := sc.Err(); err != nil {
+		return err
+	}
+	if line != "" {
+		if err := parseProperty(line, data); err != nil {
+			return xerrors.Errorf("line %d: %w", start, err)
+		}
+	}
+	return nil
+}
+
+// continues tells whether s ends with an odd number of backslashes.
+func continues(s string) bool {
+	n := 0
+	for i := len(s) - 1; i >= 0 && s[i] == '\\'; i-- {
+		n++
+	}
+	return n%2 == 1
+}
+
+// parseProperty splits line at the first unescaped "=", ":" or space, and stores the unescaped
+// key and value in data.
+func parseProperty(line string, data Map) error {
+	end := len(line)
+	for i := 0; i < len(line); i++ {
+		if line[i] == '\\' {
+			i++
+			continue
+		}
+		if strings.IndexByte("=: \t\f", line[i]) >= 0 {
+			end = i
+			break
+		}
+	}
+	rest := strings.TrimLeft(line[end:], " \t\f")
+	if rest != "" && (rest[0] == '=' || rest[0] == ':') {
+		rest = strings.TrimLeft(rest[1:], " \t\f")
+	}
+
+	k, err := unescapeProperty(line[:end])
+	if err != nil {
+		return err
+	}
+	v, err := unescapeProperty(rest)
+	if err != nil {
+		return err
+	}
+	data[k] = v
+	return nil
+}
+
+// unescapeProperty decodes "\t", "\n", "\r", "\f" and "\uXXXX". Other escaped characters stand
+// for themselves.
+func unescapeProperty(s string) (string, error) {
+	if !strings.Contains(s, `\`) {
+		return s, nil
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c != '\\' || i+1 == len(s) {
+			b.WriteByte(c)
+			continue
+		}
+		i++
+		switch c = s[i]; c {
+		case 't':
+			b.WriteByte('\t')
+		case 'n':
+			b.WriteByte('\n')
+		case 'r':
+			b.WriteByte('\r')
+		case 'f':
+			b.WriteByte('\f')
+		case 'u':
+			if i+5 > len(s) {
+				return "", fmt.Errorf("malformed \\u escape in %q", s)
+			}
+			r, err := strconv.ParseUint(s[i+1:i+5], 16, 16)
+			if err != nil {
+				return "", fmt.Errorf("malformed \\u escape in %q", s)
+			}
+			i += 4
+			// Characters outside the BMP are escaped as UTF-16 surrogate pairs.
+			if utf16.IsSurrogate(rune(r)) && strings.HasPrefix(s[i+1:], `\u`) && i+7 <= len(s) {
+				if r2, err := strconv.ParseUint(s[i+3:i+7], 16, 16); err == nil {
+					if dec := utf16.DecodeRune(rune(r), rune(r2)); dec != unicode.ReplacementChar {
+						b.WriteRune(dec)
+						i += 6
+						continue
+					}
+				}
+			}
+			b.WriteRune(rune(r))
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String(), nil
+}
@@ -0,0 +1,68 @@
+package lookup_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/carloslenz/lookup"
+)
+
+func TestProperties(t *testing.T) {
+	dir, err := ioutil.TempDir("", "lookup")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	filename := filepath.Join(dir, "app.properties")
+	contents := `# Comment
+! Another comment
+db.host = localhost
+db.port:5432
+db.name    app
+message=Hello, \
+        world
+path=C:\\temp\\
+unicode=caf\u00e9 \ud83d\ude00
+key\ with\ spaces=x\ty
+empty=
+`
+	if err := ioutil.WriteFile(filename, []byte(contents), 0666); err != nil {
+		t.Fatal(err)
+	}
+
+	l := lookup.NewProperties(filename)
+	tests := []struct {
+		key, val string
+		found    bool
+	}{
+		{"db.host", "localhost", true},
+		{"db.port", "5432", true},
+		{"db.name", "app", true},
+		{"message", "Hello, world", true},
+		{"path", `C:\temp\`, true},
+		{"unicode", "caf\u00e9 \U0001F600", true},
+		{"key with spaces", "x\ty", true},
+		{"empty", "", true},
+		{"# Comment", "", false},
+	}
+	for _, test := range tests {
+		t.Run(test.key, func(t *testing.T) {
+			v, ok, err := l.LookupKey(test.key)
+			if v != test.val || ok != test.found || err != nil {
+				t.Errorf("Unexpected result: got %q/%t/%v, expecting %q/%t", v, ok, err, test.val, test.found)
+			}
+		})
+	}
+
+	if err := ioutil.WriteFile(filename, []byte("a=1\nb=\\u12\n"), 0666); err != nil {
+		t.Fatal(err)
+	}
+	_, _, err = lookup.NewProperties(filename).LookupKey("a")
+	if err == nil || !strings.Contains(err.Error(), "line 2") {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
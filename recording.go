@@ -0,0 +1,33 @@
+package lookup
+
+import "sync"
+
+// RecordingMap is a Map that records the keys looked up, for tests.
+type RecordingMap struct {
+	Map
+
+	mutex    sync.Mutex
+	accessed []string
+}
+
+// NewRecordingMap returns a RecordingMap that serves values from m.
+func NewRecordingMap(m Map) *RecordingMap {
+	return &RecordingMap{
+		Map: m,
+	}
+}
+
+// LookupKey records s and searches it in map.
+func (l *RecordingMap) LookupKey(s string) (string, bool, error) {
+	l.mutex.Lock()
+	l.accessed = append(l.accessed, s)
+	l.mutex.Unlock()
+	return l.Map.LookupKey(s)
+}
+
+// Accessed returns the keys looked up so far, in order, including repetitions.
+func (l *RecordingMap) Accessed() []string {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	return append([]string(nil), l.accessed...)
+}
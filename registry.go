@@ -0,0 +1,83 @@
+package lookup
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+var registry = struct {
+	sync.RWMutex
+	factories map[string]func() interface{}
+}{
+	factories: make(map[string]func() interface{}),
+}
+
+var parsers = struct {
+	sync.RWMutex
+	m map[string]func(string) (interface{}, error)
+}{
+	m: make(map[string]func(string) (interface{}, error)),
+}
+
+// RegisterFieldParser makes fn available to fields tagged with ",parser=NAME", for formats that
+// don't fit fmt.Scanner. fn must return values assignable to the fields. Registering the same name
+// again replaces the parser.
+func RegisterFieldParser(name string, fn func(string) (interface{}, error)) {
+	parsers.Lock()
+	parsers.m[name] = fn
+	parsers.Unlock()
+}
+
+// setParsed sets field with the value returned by the parser registered as name.
+func setParsed(field reflect.Value, v, name string) (interface{}, error) {
+	parsers.RLock()
+	fn, ok := parsers.m[name]
+	parsers.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("parser %q is not registered", name)
+	}
+
+	e, err := fn(v)
+	if err != nil {
+		return nil, err
+	}
+	rv := reflect.ValueOf(e)
+	if e == nil || !rv.Type().AssignableTo(field.Type()) {
+		return nil, fmt.Errorf("parser %q returned %T, which is not assignable to %s", name, e, field.Type())
+	}
+	field.Set(rv)
+	return e, nil
+}
+
+// RegisterType makes factory available to interface fields whose value is name. Registering the
+// same name again replaces the factory.
+func RegisterType(name string, factory func() interface{}) {
+	registry.Lock()
+	registry.factories[name] = factory
+	registry.Unlock()
+}
+
+// lookupRegistered sets the interface field with the value created by the factory registered as
+// name. Pointers to struct are filled in with prefixed keys.
+func (s *lookupState) lookupRegistered(field reflect.Value, name, prefix, path string) error {
+	registry.RLock()
+	factory, ok := registry.factories[name]
+	registry.RUnlock()
+	if !ok {
+		return fmt.Errorf("type %q is not registered", name)
+	}
+
+	e := factory()
+	v := reflect.ValueOf(e)
+	if e == nil || !v.Type().Implements(field.Type()) {
+		return fmt.Errorf("type %q registered as %T does not implement %s", name, e, field.Type())
+	}
+	if isNested(v.Type()) && v.Kind() == reflect.Ptr && !v.IsNil() {
+		if _, err := s.lookupStruct(v.Elem(), prefix, path, false); err != nil {
+			return err
+		}
+	}
+	field.Set(v)
+	return nil
+}
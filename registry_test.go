@@ -0,0 +1,86 @@
+package lookup_test
+
+import (
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/carloslenz/lookup"
+)
+
+type backend interface {
+	Name() string
+}
+
+type redisBackend struct {
+	Addr string `lookup:"ADDR"`
+}
+
+func (b *redisBackend) Name() string { return "redis" }
+
+func TestRegisterType(t *testing.T) {
+	lookup.RegisterType("redis", func() interface{} { return new(redisBackend) })
+	lookup.RegisterType("invalid", func() interface{} { return 1 })
+
+	type conf struct {
+		Backend backend `lookup:"BACKEND"`
+	}
+	var c conf
+	if err := lookup.Lookup(&c, nil, lookup.Map{"BACKEND": "redis", "BACKEND_ADDR": "localhost:6379"}); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	b, ok := c.Backend.(*redisBackend)
+	if !ok || b.Addr != "localhost:6379" {
+		t.Errorf("Unexpected backend: %#v", c.Backend)
+	}
+
+	for _, name := range []string{"memcached", "invalid"} {
+		if err := lookup.Lookup(&c, nil, lookup.Map{"BACKEND": name}); err == nil {
+			t.Errorf("Type %q cannot be used, why no error?!", name)
+		}
+	}
+}
+
+type endpoint struct {
+	Host string
+	Port int
+}
+
+func TestRegisterFieldParser(t *testing.T) {
+	lookup.RegisterFieldParser("endpoint", func(s string) (interface{}, error) {
+		host, port, err := net.SplitHostPort(s)
+		if err != nil {
+			return nil, err
+		}
+		n, err := strconv.Atoi(port)
+		return endpoint{host, n}, err
+	})
+
+	var c struct {
+		Addr endpoint `lookup:"ADDR,parser=endpoint"`
+	}
+	if err := lookup.Lookup(&c, nil, lookup.Map{"ADDR": "localhost:80"}); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if expected := (endpoint{"localhost", 80}); c.Addr != expected {
+		t.Errorf("Unexpected result: got %#v, expecting %#v", c.Addr, expected)
+	}
+	if err := lookup.Lookup(&c, nil, lookup.Map{"ADDR": "localhost"}); err == nil {
+		t.Error("Port is missing, why no error?!")
+	}
+
+	var wrongType struct {
+		Addr string `lookup:"ADDR,parser=endpoint"`
+	}
+	err := lookup.Lookup(&wrongType, nil, lookup.Map{"ADDR": "localhost:80"})
+	if err == nil || !strings.Contains(err.Error(), "not assignable") {
+		t.Errorf("Unexpected error for wrong type: %v", err)
+	}
+	var unknown struct {
+		Addr string `lookup:"ADDR,parser=unknown"`
+	}
+	if err := lookup.Lookup(&unknown, nil, lookup.Map{"ADDR": "x"}); err == nil {
+		t.Error("Parser is not registered, why no error?!")
+	}
+}
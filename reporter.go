@@ -1,9 +1,16 @@
 package lookup
 
 import (
+	"encoding/base64"
+	"encoding/json"
 	"fmt"
 	"io"
+	"net/http"
 	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 )
 
 type (
@@ -12,63 +19,367 @@ type (
 		Report(key string, e interface{})
 	}
 
+	// MissingReporter can be implemented by Reporters to be told about optional keys that were not
+	// found. Otherwise, Report is called with an empty value for them.
+	MissingReporter interface {
+		ReportMissing(key string)
+	}
+
+	// DeprecationReporter can be implemented by Reporters to be told when a key tagged as
+	// deprecated is found. message is the text after "deprecated=", if any.
+	DeprecationReporter interface {
+		ReportDeprecated(key, message string)
+	}
+
+	// ErrorReporter can be implemented by Reporters to be told about errors skipped because of
+	// SkipOnError.
+	ErrorReporter interface {
+		ReportError(key string, err error)
+	}
+
+	// AliasReporter can be implemented by Reporters to be told when key was found as alias
+	// instead (see Options.EnvFallback).
+	AliasReporter interface {
+		ReportAlias(key, alias string)
+	}
+
+	// SourceReporter can be implemented by Reporters to be told, after each Report of a value
+	// found, the source that provided it: the name of a NamedLooker item of seq (or of the tag
+	// ",source="), of the source of a Chain item, the index of other items, or DefaultSource for
+	// values of ",default=" tags.
+	SourceReporter interface {
+		ReportSource(key, source string)
+	}
+
+	// ShadowReporter can be implemented by Reporters to be told when key, found in source winner
+	// with value winning, is also in a later source, whose value is therefore ignored (see
+	// Options.DetectShadowing). Sources are named like for SourceReporter. Values are strings as
+	// returned by the lookers.
+	ShadowReporter interface {
+		ReportShadowed(key, source, value, winner, winning string)
+	}
+
+	// Flusher is implemented by Reporters that buffer entries (e.g, DotEnvReporter). See CloseAll.
+	Flusher interface {
+		Flush() error
+	}
+
 	// FilterSecretsReporter forwards calls to Reporter replacing hidding the values of protected keys.
 	FilterSecretsReporter struct {
 		Reporter
 		*regexp.Regexp
+		// Mask replaces "(not empty)" with asterisks hinting at the length: "***" for less than 8
+		// chars, "******" up to 16 and "*********" above that.
+		Mask bool
+		// Matcher, if not nil, is used instead of Regexp (e.g, an AtomicRegexp to change the
+		// protected keys while the reporter is in use).
+		Matcher interface {
+			MatchString(string) bool
+		}
 	}
 
 	// FmtReporter outputs key-value pairs using fmt.Fprintf.
 	FmtReporter struct {
 		io.Writer
 		Prefix string
+		// Format receives Prefix, key and value, in that order. Use explicit argument indexes to
+		// reorder or skip them (e.g, "%[2]s=%[3]q\n" for logfmt). Empty means DefaultFmtFormat.
+		Format string
 	}
 
-	// MapReporter stores key-value pairs a Map.
+	// MapReporter stores key-value pairs a Map. It is safe for concurrent use.
 	MapReporter struct {
-		dest Map
+		mutex   *sync.Mutex
+		dest    Map
+		missing map[string]bool
 	}
 
 	discardReporter struct{}
 )
 
 // Report forwards calls to embedded Reporter replacing protected entries with "(empty)" or
-// "(not empty)".
+// "(not empty)" (or a mask, see Mask).
 func (r FilterSecretsReporter) Report(key string, e interface{}) {
 	var v string
 	if e != nil {
 		v = fmt.Sprint(e)
 	}
-	if r.Regexp.MatchString(key) {
-		if v == "" {
-			v = "(empty)"
-		} else {
-			v = "(not empty)"
-		}
+	r.Reporter.Report(key, r.filter(key, v))
+}
+
+// filter returns v, or its replacement if key is protected.
+func (r FilterSecretsReporter) filter(key, v string) string {
+	if !r.protected(key) {
+		return v
+	}
+	switch {
+	case v == "":
+		return "(empty)"
+	case r.Mask:
+		return maskLength(len(v))
+	default:
+		return "(not empty)"
 	}
-	r.Reporter.Report(key, v)
 }
 
+func (r FilterSecretsReporter) protected(key string) bool {
+	if r.Matcher != nil {
+		return r.Matcher.MatchString(key)
+	}
+	return r.Regexp.MatchString(key)
+}
+
+// AtomicRegexp is a regexp that can be replaced safely while other goroutines use it, e.g. as
+// FilterSecretsReporter.Matcher shared by Lookup calls with different redaction rules.
+type AtomicRegexp struct {
+	v atomic.Value // *regexp.Regexp
+}
+
+// NewAtomicRegexp creates a new AtomicRegexp set to rex.
+func NewAtomicRegexp(rex *regexp.Regexp) *AtomicRegexp {
+	r := new(AtomicRegexp)
+	r.Set(rex)
+	return r
+}
+
+// Set replaces the regexp.
+func (r *AtomicRegexp) Set(rex *regexp.Regexp) {
+	r.v.Store(rex)
+}
+
+// MatchString reports whether the current regexp matches s.
+func (r *AtomicRegexp) MatchString(s string) bool {
+	return r.v.Load().(*regexp.Regexp).MatchString(s)
+}
+
+// ReportMissing is forwarded to embedded Reporter, or reported as "(empty)" if it is not a
+// MissingReporter.
+func (r FilterSecretsReporter) ReportMissing(key string) {
+	if mr, ok := r.Reporter.(MissingReporter); ok {
+		mr.ReportMissing(key)
+		return
+	}
+	r.Report(key, "")
+}
+
+// ReportDeprecated is forwarded to embedded Reporter.
+func (r FilterSecretsReporter) ReportDeprecated(key, message string) {
+	reportDeprecated(r.Reporter, key, message)
+}
+
+// ReportAlias is forwarded to embedded Reporter.
+func (r FilterSecretsReporter) ReportAlias(key, alias string) {
+	reportAlias(r.Reporter, key, alias)
+}
+
+// ReportSource is forwarded to embedded Reporter.
+func (r FilterSecretsReporter) ReportSource(key, source string) {
+	reportSource(r.Reporter, key, source)
+}
+
+// ReportShadowed is forwarded to embedded Reporter, replacing the values of protected keys like
+// Report.
+func (r FilterSecretsReporter) ReportShadowed(key, source, value, winner, winning string) {
+	reportShadowed(r.Reporter, key, source, r.filter(key, value), winner, r.filter(key, winning))
+}
+
+// ReportError is forwarded to embedded Reporter.
+func (r FilterSecretsReporter) ReportError(key string, err error) {
+	reportError(r.Reporter, key, err)
+}
+
+// Flush is forwarded to embedded Reporter, if it is a Flusher.
+func (r FilterSecretsReporter) Flush() error {
+	return flush(r.Reporter)
+}
+
+func maskLength(n int) string {
+	switch {
+	case n < 8:
+		return "***"
+	case n <= 16:
+		return "******"
+	default:
+		return "*********"
+	}
+}
+
+// DefaultFmtFormat is the layout used by FmtReporter when Format is empty.
+const DefaultFmtFormat = "%s%s=%v\n"
+
 // Report outputs to embedded Writer.
 func (r FmtReporter) Report(key string, e interface{}) {
-	fmt.Fprintf(r.Writer, "%s%s=%v\n", r.Prefix, key, e)
+	format := r.Format
+	if format == "" {
+		format = DefaultFmtFormat
+	}
+	fmt.Fprintf(r.Writer, format, r.Prefix, key, e)
 }
 
 // NewMapReporter creates a new MapReporter.
 func NewMapReporter() MapReporter {
 	return MapReporter{
-		dest: make(Map),
+		mutex:   new(sync.Mutex),
+		dest:    make(Map),
+		missing: make(map[string]bool),
 	}
 }
 
 // Report stores key and e into an internal Map.
 func (r MapReporter) Report(key string, e interface{}) {
-	r.dest[key] = fmt.Sprint(e)
+	v := fmt.Sprint(e)
+	r.mutex.Lock()
+	r.dest[key] = v
+	delete(r.missing, key)
+	r.mutex.Unlock()
+}
+
+// ReportMissing records key for Missing. It is left out of Map.
+func (r MapReporter) ReportMissing(key string) {
+	r.mutex.Lock()
+	r.missing[key] = true
+	r.mutex.Unlock()
 }
 
-// Map returns the Map with stored key-value pairs.
+// Missing returns the optional keys that were not found, sorted.
+func (r MapReporter) Missing() []string {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return mapKeys(r.missing)
+}
+
+// Map returns a copy of the Map with stored key-value pairs.
 func (r MapReporter) Map() Map {
-	return r.dest
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	m := make(Map, len(r.dest))
+	for k, v := range r.dest {
+		m[k] = v
+	}
+	return m
+}
+
+// Entry is a key-value pair stored by OrderedMapReporter.
+type Entry struct {
+	Key, Value string
+}
+
+// OrderedMapReporter stores key-value pairs in the order they are reported, which for Lookup is the
+// order of struct fields.
+type OrderedMapReporter struct {
+	entries []Entry
+	index   map[string]int
+}
+
+// NewOrderedMapReporter creates a new OrderedMapReporter.
+func NewOrderedMapReporter() *OrderedMapReporter {
+	return &OrderedMapReporter{
+		index: make(map[string]int),
+	}
+}
+
+// Report stores key and e. Keys reported again keep their original position.
+func (r *OrderedMapReporter) Report(key string, e interface{}) {
+	v := fmt.Sprint(e)
+	if i, ok := r.index[key]; ok {
+		r.entries[i].Value = v
+		return
+	}
+	r.index[key] = len(r.entries)
+	r.entries = append(r.entries, Entry{Key: key, Value: v})
+}
+
+// Entries returns the stored key-value pairs in the order they were first reported.
+func (r *OrderedMapReporter) Entries() []Entry {
+	return r.entries
+}
+
+// LeveledReporter forwards entries that differ from Defaults to Changed, and the others to
+// Unchanged, if not nil. For instance, Changed can log at INFO level and Unchanged at DEBUG. Calls
+// that are not about values, like ReportDeprecated, are forwarded to Changed.
+type LeveledReporter struct {
+	Changed, Unchanged Reporter
+	Defaults           Map
+}
+
+// NewLeveledReporter creates a LeveledReporter that drops entries matching defaults.
+func NewLeveledReporter(inner Reporter, defaults Map) LeveledReporter {
+	return LeveledReporter{
+		Changed:  inner,
+		Defaults: defaults,
+	}
+}
+
+// Report compares e with the default for key, as a string ([]byte is encoded as base64).
+func (r LeveledReporter) Report(key string, e interface{}) {
+	var v string
+	if b, ok := e.([]byte); ok {
+		v = base64.RawStdEncoding.EncodeToString(b)
+	} else {
+		v = fmt.Sprint(e)
+	}
+	if d, ok := r.Defaults[key]; !ok || d != v {
+		r.Changed.Report(key, e)
+	} else if r.Unchanged != nil {
+		r.Unchanged.Report(key, e)
+	}
+}
+
+// ReportMissing is forwarded like Report with an empty value.
+func (r LeveledReporter) ReportMissing(key string) {
+	if d, ok := r.Defaults[key]; !ok || d != "" {
+		reportMissing(r.Changed, key)
+	} else if r.Unchanged != nil {
+		reportMissing(r.Unchanged, key)
+	}
+}
+
+// ReportDeprecated is forwarded to Changed.
+func (r LeveledReporter) ReportDeprecated(key, message string) {
+	reportDeprecated(r.Changed, key, message)
+}
+
+// ReportAlias is forwarded to Changed.
+func (r LeveledReporter) ReportAlias(key, alias string) {
+	reportAlias(r.Changed, key, alias)
+}
+
+// ReportSource is forwarded to Changed.
+func (r LeveledReporter) ReportSource(key, source string) {
+	reportSource(r.Changed, key, source)
+}
+
+// ReportShadowed is forwarded to Changed.
+func (r LeveledReporter) ReportShadowed(key, source, value, winner, winning string) {
+	reportShadowed(r.Changed, key, source, value, winner, winning)
+}
+
+// ReportError is forwarded to Changed.
+func (r LeveledReporter) ReportError(key string, err error) {
+	reportError(r.Changed, key, err)
+}
+
+// Flush is forwarded to Changed and Unchanged, if they are Flushers. Returns the first error.
+func (r LeveledReporter) Flush() error {
+	err := flush(r.Changed)
+	if r.Unchanged != nil {
+		if uerr := flush(r.Unchanged); err == nil {
+			err = uerr
+		}
+	}
+	return err
+}
+
+// DiffDefaults returns the entries in resolved (e.g, from MapReporter) whose values differ from
+// defaults, including those that have no default.
+func DiffDefaults(resolved, defaults Map) Map {
+	diff := make(Map)
+	for k, v := range resolved {
+		if d, ok := defaults[k]; !ok || d != v {
+			diff[k] = v
+		}
+	}
+	return diff
 }
 
 // DupReporter forwards Report calls to all items.
@@ -81,6 +392,243 @@ func (r DupReporter) Report(key string, e interface{}) {
 	}
 }
 
+// ReportMissing is forwarded to all items.
+func (r DupReporter) ReportMissing(key string) {
+	for _, v := range r {
+		reportMissing(v, key)
+	}
+}
+
+// ReportDeprecated is forwarded to all items.
+func (r DupReporter) ReportDeprecated(key, message string) {
+	for _, v := range r {
+		reportDeprecated(v, key, message)
+	}
+}
+
+// ReportAlias is forwarded to all items.
+func (r DupReporter) ReportAlias(key, alias string) {
+	for _, v := range r {
+		reportAlias(v, key, alias)
+	}
+}
+
+// ReportSource is forwarded to all items.
+func (r DupReporter) ReportSource(key, source string) {
+	for _, v := range r {
+		reportSource(v, key, source)
+	}
+}
+
+// ReportShadowed is forwarded to all items.
+func (r DupReporter) ReportShadowed(key, source, value, winner, winning string) {
+	for _, v := range r {
+		reportShadowed(v, key, source, value, winner, winning)
+	}
+}
+
+// Flush is forwarded to all items implementing Flusher. Returns the first error.
+func (r DupReporter) Flush() error {
+	var first error
+	for _, v := range r {
+		if err := flush(v); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+func flush(r Reporter) error {
+	if f, ok := r.(Flusher); ok {
+		return f.Flush()
+	}
+	return nil
+}
+
+func reportDeprecated(r Reporter, key, message string) {
+	if dr, ok := r.(DeprecationReporter); ok {
+		dr.ReportDeprecated(key, message)
+	}
+}
+
+func reportAlias(r Reporter, key, alias string) {
+	if ar, ok := r.(AliasReporter); ok {
+		ar.ReportAlias(key, alias)
+	}
+}
+
+// DefaultSource is reported to SourceReporter for values of ",default=" tags.
+const DefaultSource = "(default)"
+
+func reportSource(r Reporter, key, source string) {
+	if sr, ok := r.(SourceReporter); ok {
+		sr.ReportSource(key, source)
+	}
+}
+
+func reportShadowed(r Reporter, key, source, value, winner, winning string) {
+	if sr, ok := r.(ShadowReporter); ok {
+		sr.ReportShadowed(key, source, value, winner, winning)
+	}
+}
+
+// ReportError is forwarded to all items.
+func (r DupReporter) ReportError(key string, err error) {
+	for _, v := range r {
+		reportError(v, key, err)
+	}
+}
+
+func reportError(r Reporter, key string, err error) {
+	if er, ok := r.(ErrorReporter); ok {
+		er.ReportError(key, err)
+	}
+}
+
+func reportMissing(r Reporter, key string) {
+	if mr, ok := r.(MissingReporter); ok {
+		mr.ReportMissing(key)
+		return
+	}
+	r.Report(key, "")
+}
+
+// ReportTransformer forwards calls to Reporter replacing values with what Transform returns (e.g,
+// "<PEM>" for certificates). Struct fields filled in by Lookup keep the original values.
+type ReportTransformer struct {
+	Reporter
+	Transform func(key string, e interface{}) interface{}
+}
+
+// Report forwards Transform(key, e) to embedded Reporter.
+func (r ReportTransformer) Report(key string, e interface{}) {
+	r.Reporter.Report(key, r.Transform(key, e))
+}
+
+// ReportMissing is forwarded to embedded Reporter.
+func (r ReportTransformer) ReportMissing(key string) {
+	reportMissing(r.Reporter, key)
+}
+
+// ReportDeprecated is forwarded to embedded Reporter.
+func (r ReportTransformer) ReportDeprecated(key, message string) {
+	reportDeprecated(r.Reporter, key, message)
+}
+
+// ReportAlias is forwarded to embedded Reporter.
+func (r ReportTransformer) ReportAlias(key, alias string) {
+	reportAlias(r.Reporter, key, alias)
+}
+
+// ReportSource is forwarded to embedded Reporter.
+func (r ReportTransformer) ReportSource(key, source string) {
+	reportSource(r.Reporter, key, source)
+}
+
+// ReportShadowed is forwarded to embedded Reporter, with values replaced by what Transform
+// returns.
+func (r ReportTransformer) ReportShadowed(key, source, value, winner, winning string) {
+	value = fmt.Sprint(r.Transform(key, value))
+	winning = fmt.Sprint(r.Transform(key, winning))
+	reportShadowed(r.Reporter, key, source, value, winner, winning)
+}
+
+// ReportError is forwarded to embedded Reporter.
+func (r ReportTransformer) ReportError(key string, err error) {
+	reportError(r.Reporter, key, err)
+}
+
+// Flush is forwarded to embedded Reporter, if it is a Flusher.
+func (r ReportTransformer) Flush() error {
+	return flush(r.Reporter)
+}
+
+// SnapshotReporter records entries and serves the last published set as JSON, e.g. at
+// /debug/config. Entries are published by Flush, after Lookup succeeds, so readers never see a
+// partial reload. Wrap it in FilterSecretsReporter to mask secrets.
+type SnapshotReporter struct {
+	mutex   sync.Mutex
+	pending Map
+	current atomic.Value // Map
+}
+
+// NewSnapshotReporter creates a new SnapshotReporter with an empty snapshot.
+func NewSnapshotReporter() *SnapshotReporter {
+	r := &SnapshotReporter{
+		pending: make(Map),
+	}
+	r.current.Store(Map{})
+	return r
+}
+
+// Report stores key and e until Flush.
+func (r *SnapshotReporter) Report(key string, e interface{}) {
+	v := fmt.Sprint(e)
+	r.mutex.Lock()
+	r.pending[key] = v
+	r.mutex.Unlock()
+}
+
+// Flush replaces the snapshot with the entries reported since the last call.
+func (r *SnapshotReporter) Flush() error {
+	r.mutex.Lock()
+	m := r.pending
+	r.pending = make(Map)
+	r.mutex.Unlock()
+	r.current.Store(m)
+	return nil
+}
+
+// Snapshot returns the entries published by the last Flush. It must not be modified.
+func (r *SnapshotReporter) Snapshot() Map {
+	return r.current.Load().(Map)
+}
+
+// ServeHTTP writes the snapshot as a JSON object, sorted by key.
+func (r *SnapshotReporter) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.Encode(r.Snapshot())
+}
+
+// DotEnvReporter writes entries as a .env file, sorted by key, when Flush is called.
+type DotEnvReporter struct {
+	w       io.Writer
+	entries Map
+}
+
+// NewDotEnvReporter creates a new DotEnvReporter that writes to w.
+func NewDotEnvReporter(w io.Writer) *DotEnvReporter {
+	return &DotEnvReporter{
+		w:       w,
+		entries: make(Map),
+	}
+}
+
+// Report stores key and e until Flush. []byte values are encoded as base64, like Lookup expects.
+func (r *DotEnvReporter) Report(key string, e interface{}) {
+	if b, ok := e.([]byte); ok {
+		r.entries[key] = base64.RawStdEncoding.EncodeToString(b)
+		return
+	}
+	r.entries[key] = fmt.Sprint(e)
+}
+
+// Flush writes all stored entries as KEY=VALUE lines, quoting values when needed.
+func (r *DotEnvReporter) Flush() error {
+	for _, k := range r.entries.Keys() {
+		v := r.entries[k]
+		if strings.ContainsAny(v, " \t\r\n\"'\\#$`=") {
+			v = strconv.Quote(v)
+		}
+		if _, err := fmt.Fprintf(r.w, "%s=%s\n", k, v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 var discard discardReporter
 
 func (r discardReporter) Report(key string, e interface{}) {}
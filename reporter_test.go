@@ -3,11 +3,16 @@ package lookup_test
 import (
 	"bytes"
 	"flag"
+	"fmt"
 	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
 	"reflect"
 	"regexp"
+	"strconv"
+	"sync"
 	"testing"
 
 	"github.com/carloslenz/lookup"
@@ -45,14 +50,16 @@ func TestReporter(t *testing.T) {
 		t.Fatal(err)
 	}
 	expectedMap := lookup.Map{
-		"ANY_SECRET":     "007 identity",
-		"SECRET_AS_WELL": "",
-		"PUBLIC":         "Old news",
-		"ON_THE_RECORD":  "Everybody knows",
+		"ANY_SECRET":    "007 identity",
+		"PUBLIC":        "Old news",
+		"ON_THE_RECORD": "Everybody knows",
 	}
 	if !reflect.DeepEqual(mr.Map(), expectedMap) {
 		t.Errorf("Unexpected Map:\n***got***\n%v\n***\n%v", mr.Map(), expectedMap)
 	}
+	if expected := []string{"SECRET_AS_WELL"}; !reflect.DeepEqual(mr.Missing(), expected) {
+		t.Errorf("Unexpected missing keys: %q, expecting %q", mr.Missing(), expected)
+	}
 	expectedData := cfg{
 		AnySecret:    "007 identity",
 		SecretAsWell: "",
@@ -74,3 +81,403 @@ func TestReporter(t *testing.T) {
 		t.Errorf("Unexpected output:\n***got***\n%s\n***expecting***\n%s", s, expected)
 	}
 }
+
+func TestFmtReporterFormat(t *testing.T) {
+	tests := []struct {
+		format, expected string
+	}{
+		{"", "- PORT=8080\n"},
+		{"%[2]s=%[3]q\n", "PORT=\"8080\"\n"},
+		{"%[1]s%[2]s => %[3]v\n", "- PORT => 8080\n"},
+	}
+	for _, test := range tests {
+		t.Run(test.format, func(t *testing.T) {
+			var b bytes.Buffer
+			r := lookup.FmtReporter{Writer: &b, Prefix: "- ", Format: test.format}
+			r.Report("PORT", "8080")
+			if s := b.String(); s != test.expected {
+				t.Errorf("Unexpected output: got %q, expecting %q", s, test.expected)
+			}
+		})
+	}
+}
+
+func TestFilterSecretsReporterMask(t *testing.T) {
+	mr := lookup.NewMapReporter()
+	r := lookup.FilterSecretsReporter{
+		Reporter: mr,
+		Regexp:   regexp.MustCompile(`SECRET`),
+		Mask:     true,
+	}
+	r.Report("EMPTY_SECRET", "")
+	r.Report("SHORT_SECRET", "007")
+	r.Report("MEDIUM_SECRET", "0123456789")
+	r.Report("LONG_SECRET", "0123456789abcdefghij")
+	r.Report("PUBLIC", "0123456789")
+
+	expected := lookup.Map{
+		"EMPTY_SECRET":  "(empty)",
+		"SHORT_SECRET":  "***",
+		"MEDIUM_SECRET": "******",
+		"LONG_SECRET":   "*********",
+		"PUBLIC":        "0123456789",
+	}
+	if !reflect.DeepEqual(mr.Map(), expected) {
+		t.Errorf("Unexpected Map:\n***got***\n%v\n***expecting***\n%v", mr.Map(), expected)
+	}
+}
+
+func TestDotEnvReporter(t *testing.T) {
+	var b bytes.Buffer
+	r := lookup.NewDotEnvReporter(&b)
+	r.Report("PORT", 8080)
+	r.Report("NAME", "my server")
+	r.Report("KEY", []byte("secret"))
+	r.Report("EMPTY", "")
+	if err := r.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	expected := `EMPTY=
+KEY=c2VjcmV0
+NAME="my server"
+PORT=8080
+`
+	if s := b.String(); s != expected {
+		t.Errorf("Unexpected output:\n***got***\n%s\n***expecting***\n%s", s, expected)
+	}
+}
+
+type missingEntries struct {
+	entries
+	missing []string
+}
+
+func (e *missingEntries) ReportMissing(key string) {
+	e.missing = append(e.missing, key)
+}
+
+func TestMissingReporter(t *testing.T) {
+	var c struct {
+		A string `lookup:"A,optional"`
+		B string `lookup:"B,optional"`
+	}
+	var plain entries
+	var e missingEntries
+	r := lookup.DupReporter{&plain, &e}
+	if err := lookup.Lookup(&c, r, lookup.Map{"A": ""}); err != nil {
+		t.Fatal(err)
+	}
+
+	if expected := (entries{"A", "", "B", ""}); !reflect.DeepEqual(plain, expected) {
+		t.Errorf("Unexpected reports: %#v, expecting %#v", plain, expected)
+	}
+	if expected := (entries{"A", ""}); !reflect.DeepEqual(e.entries, expected) {
+		t.Errorf("Unexpected reports: %#v, expecting %#v", e.entries, expected)
+	}
+	if expected := []string{"B"}; !reflect.DeepEqual(e.missing, expected) {
+		t.Errorf("Unexpected missing reports: %#v, expecting %#v", e.missing, expected)
+	}
+}
+
+func TestDiffDefaults(t *testing.T) {
+	defaults := lookup.Map{"PORT": "8080", "HOST": "localhost", "UNUSED": "x"}
+	mr := lookup.NewMapReporter()
+	lookup.LookupMap([]string{"PORT", "HOST", "DEBUG"}, nil, mr, lookup.Map{"PORT": "9090", "DEBUG": "1"}, defaults)
+
+	diff := lookup.DiffDefaults(mr.Map(), defaults)
+	expected := lookup.Map{"PORT": "9090", "DEBUG": "1"}
+	if !reflect.DeepEqual(diff, expected) {
+		t.Errorf("Unexpected diff: %v, expecting %v", diff, expected)
+	}
+}
+
+type deprecations []string
+
+func (d *deprecations) Report(key string, e interface{}) {}
+
+func (d *deprecations) ReportDeprecated(key, message string) {
+	*d = append(*d, key, message)
+}
+
+func TestDeprecationReporter(t *testing.T) {
+	var c struct {
+		Host    string `lookup:"HOST,optional,deprecated=use ADDR instead"`
+		Port    string `lookup:"PORT,optional,deprecated"`
+		Timeout string `lookup:"TIMEOUT,optional,deprecated"`
+	}
+	var d deprecations
+	r := lookup.DupReporter{lookup.FilterSecretsReporter{Reporter: &d, Regexp: regexp.MustCompile(`^$`)}}
+	if err := lookup.Lookup(&c, r, lookup.Map{"HOST": "localhost", "PORT": "80"}); err != nil {
+		t.Fatal(err)
+	}
+	if c.Host != "localhost" || c.Port != "80" {
+		t.Errorf("Deprecated fields should still load: %#v", c)
+	}
+	expected := deprecations{"HOST", "use ADDR instead", "PORT", ""}
+	if !reflect.DeepEqual(d, expected) {
+		t.Errorf("Unexpected deprecations: %#v, expecting %#v", d, expected)
+	}
+}
+
+func TestOrderedMapReporter(t *testing.T) {
+	var c struct {
+		Zeta  string `lookup:"ZETA"`
+		Alpha int    `lookup:"ALPHA"`
+		Mid   bool   `lookup:"MID,optional"`
+	}
+	r := lookup.NewOrderedMapReporter()
+	if err := lookup.Lookup(&c, lookup.DupReporter{r}, lookup.Map{"ZETA": "z", "ALPHA": "1"}); err != nil {
+		t.Fatal(err)
+	}
+	expected := []lookup.Entry{{"ZETA", "z"}, {"ALPHA", "1"}, {"MID", ""}}
+	if !reflect.DeepEqual(r.Entries(), expected) {
+		t.Errorf("Unexpected entries: %v, expecting %v", r.Entries(), expected)
+	}
+}
+
+func TestLeveledReporter(t *testing.T) {
+	var c struct {
+		Port     int    `lookup:"PORT"`
+		Host     string `lookup:"HOST"`
+		Password string `lookup:"PASSWORD"`
+	}
+	defaults := lookup.Map{"PORT": "8080", "HOST": "localhost", "PASSWORD": "changeme"}
+	changed := lookup.NewMapReporter()
+	unchanged := lookup.NewMapReporter()
+	r := lookup.NewLeveledReporter(lookup.FilterSecretsReporter{
+		Reporter: changed,
+		Regexp:   regexp.MustCompile(`PASSWORD`),
+	}, defaults)
+	r.Unchanged = unchanged
+
+	if err := lookup.Lookup(&c, r, lookup.Map{"PORT": "9090", "PASSWORD": "s3cr3t"}, defaults); err != nil {
+		t.Fatal(err)
+	}
+	if expected := (lookup.Map{"PORT": "9090", "PASSWORD": "(not empty)"}); !reflect.DeepEqual(changed.Map(), expected) {
+		t.Errorf("Unexpected changed: %v, expecting %v", changed.Map(), expected)
+	}
+	if expected := (lookup.Map{"HOST": "localhost"}); !reflect.DeepEqual(unchanged.Map(), expected) {
+		t.Errorf("Unexpected unchanged: %v, expecting %v", unchanged.Map(), expected)
+	}
+
+	var old struct {
+		Host string `lookup:"HOST,deprecated=use ADDR instead"`
+	}
+	var d deprecations
+	r = lookup.NewLeveledReporter(lookup.FilterSecretsReporter{Reporter: &d, Regexp: regexp.MustCompile(`^$`)}, defaults)
+	if err := lookup.Lookup(&old, r, defaults); err != nil {
+		t.Fatal(err)
+	}
+	if expected := (deprecations{"HOST", "use ADDR instead"}); !reflect.DeepEqual(d, expected) {
+		t.Errorf("Unexpected deprecations: %#v, expecting %#v", d, expected)
+	}
+}
+
+func TestReportTransformer(t *testing.T) {
+	var c struct {
+		Cert string `lookup:"CERT"`
+		Name string `lookup:"NAME"`
+	}
+	var e entries
+	r := lookup.ReportTransformer{
+		Reporter: &e,
+		Transform: func(key string, v interface{}) interface{} {
+			if key == "CERT" {
+				return "<PEM>"
+			}
+			return v
+		},
+	}
+	pem := "-----BEGIN CERTIFICATE-----\nMIIB\n-----END CERTIFICATE-----"
+	if err := lookup.Lookup(&c, r, lookup.Map{"CERT": pem, "NAME": "app"}); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if c.Cert != pem {
+		t.Errorf("Unexpected field value: got %q, expecting %q", c.Cert, pem)
+	}
+	if expected := (entries{"CERT", "<PEM>", "NAME", "app"}); !reflect.DeepEqual(e, expected) {
+		t.Errorf("Unexpected reports: %#v, expecting %#v", e, expected)
+	}
+
+	snapshot := lookup.NewSnapshotReporter()
+	r.Reporter = snapshot
+	if err := lookup.Lookup(&c, r, lookup.Map{"CERT": pem, "NAME": "app"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := lookup.CloseAll(r); err != nil {
+		t.Fatal(err)
+	}
+	if expected := (lookup.Map{"CERT": "<PEM>", "NAME": "app"}); !reflect.DeepEqual(snapshot.Snapshot(), expected) {
+		t.Errorf("Unexpected snapshot: %v, expecting %v", snapshot.Snapshot(), expected)
+	}
+}
+
+func TestMapReporterConcurrent(t *testing.T) {
+	mr := lookup.NewMapReporter()
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			var c struct {
+				A string `lookup:"A"`
+			}
+			lookup.Lookup(&c, mr, lookup.Map{"A": strconv.Itoa(i)})
+			mr.Report(strconv.Itoa(i), i)
+		}(i)
+	}
+	wg.Wait()
+
+	m := mr.Map()
+	if len(m) != 9 {
+		t.Errorf("Unexpected Map: %v", m)
+	}
+	m["B"] = "changed"
+	if _, ok := mr.Map()["B"]; ok {
+		t.Error("Map should return a copy")
+	}
+}
+
+func TestSnapshotReporter(t *testing.T) {
+	snap := lookup.NewSnapshotReporter()
+	r := lookup.FilterSecretsReporter{Reporter: snap, Regexp: regexp.MustCompile("PASSWORD")}
+	var c struct {
+		Host     string `lookup:"HOST"`
+		Password string `lookup:"PASSWORD"`
+	}
+	if err := lookup.Lookup(&c, r, lookup.Map{"HOST": "localhost", "PASSWORD": "secret"}); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if len(snap.Snapshot()) != 0 {
+		t.Errorf("Snapshot should be empty before Flush: %v", snap.Snapshot())
+	}
+	if err := lookup.CloseAll(r); err != nil {
+		t.Fatal(err)
+	}
+
+	rec := httptest.NewRecorder()
+	snap.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/debug/config", nil))
+	expected := "{\n  \"HOST\": \"localhost\",\n  \"PASSWORD\": \"(not empty)\"\n}\n"
+	if body := rec.Body.String(); body != expected {
+		t.Errorf("Unexpected body:\n***got***\n%s\n***expecting***\n%s", body, expected)
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+		t.Errorf("Unexpected Content-Type: %q", ct)
+	}
+
+	snap.Report("HOST", "other")
+	if v := snap.Snapshot()["HOST"]; v != "localhost" {
+		t.Errorf("Snapshot changed before Flush: %q", v)
+	}
+}
+
+func TestFilterSecretsReporterMatcher(t *testing.T) {
+	mr := lookup.NewMapReporter()
+	rex := lookup.NewAtomicRegexp(regexp.MustCompile("PASSWORD"))
+	r := lookup.FilterSecretsReporter{Reporter: mr, Matcher: rex}
+	r.Report("PASSWORD", "secret")
+	r.Report("TOKEN", "abc")
+
+	rex.Set(regexp.MustCompile("TOKEN"))
+	r.Report("KEY", "x")
+	r.Report("TOKEN_2", "abc")
+
+	expected := lookup.Map{"PASSWORD": "(not empty)", "TOKEN": "abc", "KEY": "x", "TOKEN_2": "(not empty)"}
+	if !reflect.DeepEqual(mr.Map(), expected) {
+		t.Errorf("Unexpected Map:\n***got***\n%v\n***expecting***\n%v", mr.Map(), expected)
+	}
+}
+
+type sources map[string]string
+
+func (s sources) Report(key string, e interface{}) {}
+
+func (s sources) ReportSource(key, source string) {
+	s[key] = source
+}
+
+func TestSourceReporter(t *testing.T) {
+	var c struct {
+		Host string `lookup:"HOST"`
+		Port int    `lookup:"PORT,default=5432"`
+		User string `lookup:"USER"`
+	}
+	s := make(sources)
+	seq := []lookup.Looker{
+		lookup.NamedLooker{Name: "env", Looker: lookup.Map{"HOST": "localhost"}},
+		lookup.Map{"USER": "admin"},
+	}
+	r := lookup.FilterSecretsReporter{Reporter: s, Regexp: regexp.MustCompile(`^$`)}
+	if err := lookup.Lookup(&c, r, seq...); err != nil {
+		t.Fatal(err)
+	}
+	expected := sources{"HOST": "env", "PORT": lookup.DefaultSource, "USER": "1"}
+	if !reflect.DeepEqual(s, expected) {
+		t.Errorf("Unexpected sources: %v, expecting %v", s, expected)
+	}
+}
+
+func TestMapReporterMissing(t *testing.T) {
+	var c struct {
+		Host  string `lookup:"HOST"`
+		Name  string `lookup:"NAME,optional"`
+		Proxy string `lookup:"PROXY,optional"`
+	}
+	mr := lookup.NewMapReporter()
+	if err := lookup.Lookup(&c, mr, lookup.Map{"HOST": "localhost", "NAME": ""}); err != nil {
+		t.Fatal(err)
+	}
+	if expected := (lookup.Map{"HOST": "localhost", "NAME": ""}); !reflect.DeepEqual(mr.Map(), expected) {
+		t.Errorf("Unexpected Map: %v, expecting %v", mr.Map(), expected)
+	}
+	if expected := []string{"PROXY"}; !reflect.DeepEqual(mr.Missing(), expected) {
+		t.Errorf("Unexpected missing keys: %q, expecting %q", mr.Missing(), expected)
+	}
+
+	mr.Report("PROXY", "http://proxy")
+	if len(mr.Missing()) != 0 {
+		t.Errorf("PROXY was reported, yet missing = %q", mr.Missing())
+	}
+}
+
+type shadows []string
+
+func (s *shadows) Report(key string, e interface{}) {}
+
+func (s *shadows) ReportShadowed(key, source, value, winner, winning string) {
+	*s = append(*s, fmt.Sprintf("%s from %s (%s) overridden by %s (%s)", key, source, value, winner, winning))
+}
+
+func TestShadowReporter(t *testing.T) {
+	var c struct {
+		Port int    `lookup:"PORT"`
+		Host string `lookup:"HOST"`
+	}
+	seq := []lookup.Looker{
+		lookup.NamedLooker{Name: "args", Looker: lookup.Map{"PORT": "9090"}},
+		lookup.NamedLooker{Name: "env", Looker: lookup.Map{"PORT": "8080", "HOST": "localhost"}},
+		lookup.Map{"PORT": "80"},
+	}
+	var s shadows
+	if err := lookup.Lookup(&c, &s, seq...); err != nil || len(s) != 0 {
+		t.Fatalf("Shadowing is not detected by default: %q/%v", s, err)
+	}
+
+	if err := (lookup.Options{DetectShadowing: true}).Lookup(&c, &s, seq...); err != nil {
+		t.Fatal(err)
+	}
+	expected := shadows{"PORT from env (8080) overridden by args (9090)", "PORT from 2 (80) overridden by args (9090)"}
+	if c.Port != 9090 || !reflect.DeepEqual(s, expected) {
+		t.Errorf("Unexpected shadows: %q, expecting %q", s, expected)
+	}
+
+	s = nil
+	r := lookup.FilterSecretsReporter{Reporter: &s, Regexp: regexp.MustCompile(`PORT`), Mask: true}
+	if err := (lookup.Options{DetectShadowing: true}).Lookup(&c, r, seq...); err != nil {
+		t.Fatal(err)
+	}
+	expected = shadows{"PORT from env (***) overridden by args (***)", "PORT from 2 (***) overridden by args (***)"}
+	if !reflect.DeepEqual(s, expected) {
+		t.Errorf("Unexpected filtered shadows: %q, expecting %q", s, expected)
+	}
+}
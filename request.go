@@ -0,0 +1,130 @@
+package lookup
+
+import (
+	"mime"
+	"net/http"
+	"net/url"
+	"sync"
+
+	"golang.org/x/xerrors"
+)
+
+// RequestLooker is a Looker for values in http.Request. Reset replaces the request, so the
+// instance can be reused (e.g, from a sync.Pool). Values cached from the previous request are
+// discarded and the new body is read by the next lookup. Reset must not be called while Lookup
+// uses the looker.
+type RequestLooker interface {
+	Looker
+	Reset(req *http.Request)
+}
+
+// maxMultipartMemory is how much of a multipart body is kept in memory (the rest goes to
+// temporary files), like in net/http.
+const maxMultipartMemory = 32 << 20
+
+type requestLooker struct {
+	*http.Request
+
+	mutex  sync.Mutex
+	data   Map
+	loaded bool
+	err    error
+}
+
+// NewRequest returns a Looker that merges the query of req with its body, which is decoded
+// according to Content-Type (JSON, urlencoded or multipart form). Query values win over body
+// values. Like NewForm, keys present but empty are read as "1". The body is read only once.
+func NewRequest(req *http.Request) RequestLooker {
+	return &requestLooker{
+		Request: req,
+	}
+}
+
+func (l *requestLooker) LookupKey(k string) (string, bool, error) {
+	if err := l.once(); err != nil {
+		return "", false, err
+	}
+	return l.data.LookupKey(k)
+}
+
+// Keys returns the keys in query and body, which is loaded if needed.
+func (l *requestLooker) Keys() []string {
+	l.once()
+	return l.data.Keys()
+}
+
+// KeysErr is like Keys, but also returns the error loading the request.
+func (l *requestLooker) KeysErr() ([]string, error) {
+	if err := l.once(); err != nil {
+		return nil, err
+	}
+	return l.data.Keys(), nil
+}
+
+// Reset clears the values loaded from the previous request and replaces it with req.
+func (l *requestLooker) Reset(req *http.Request) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.Request = req
+	for k := range l.data {
+		delete(l.data, k)
+	}
+	l.loaded = false
+	l.err = nil
+}
+
+func (l *requestLooker) once() error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if !l.loaded {
+		// If body fails to load, don't try again for the same request:
+		l.loaded = true
+		if l.data == nil {
+			l.data = make(Map)
+		}
+		l.err = l.load()
+	}
+	return l.err
+}
+
+func (l *requestLooker) load() error {
+	contentType, _, _ := mime.ParseMediaType(l.Header.Get("Content-Type"))
+	switch contentType {
+	case "application/json":
+		if l.Body == nil {
+			break
+		}
+		var body map[string]interface{}
+		err := decodeJSON(l.Body, &body)
+		l.Body.Close()
+		if err != nil {
+			return xerrors.Errorf("cannot decode JSON body: %w", err)
+		}
+		for k, v := range body {
+			if v != nil {
+				l.data[k] = jsonString(v)
+			}
+		}
+
+	case "application/x-www-form-urlencoded":
+		if err := l.ParseForm(); err != nil {
+			return xerrors.Errorf("ParseForm failed: %w", err)
+		}
+		l.setValues(l.PostForm)
+
+	case "multipart/form-data":
+		if err := l.ParseMultipartForm(maxMultipartMemory); err != nil {
+			return xerrors.Errorf("ParseMultipartForm failed: %w", err)
+		}
+		l.setValues(l.MultipartForm.Value)
+	}
+
+	l.setValues(l.URL.Query())
+	return nil
+}
+
+func (l *requestLooker) setValues(values url.Values) {
+	for k, v := range values {
+		l.data[k] = formValue(v, "1")
+	}
+}
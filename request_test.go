@@ -0,0 +1,92 @@
+package lookup_test
+
+import (
+	"bytes"
+	"mime/multipart"
+	"net/http"
+	"testing"
+
+	"github.com/carloslenz/lookup"
+)
+
+func TestRequestLooker(t *testing.T) {
+	var multipartBody bytes.Buffer
+	w := multipart.NewWriter(&multipartBody)
+	w.WriteField("A", "body")
+	w.WriteField("B", "2")
+	w.WriteField("C", "")
+	w.Close()
+
+	tests := []struct {
+		name, contentType, body string
+	}{
+		{"json", "application/json; charset=utf-8", `{"A": "body", "B": 2, "C": true}`},
+		{"form", "application/x-www-form-urlencoded", "A=body&B=2&C"},
+		{"multipart", w.FormDataContentType(), multipartBody.String()},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodPost, "/?A=query&D=4", bytes.NewBufferString(test.body))
+			if err != nil {
+				t.Fatal(err)
+			}
+			req.Header.Set("Content-Type", test.contentType)
+
+			l := lookup.NewRequest(req)
+			tests := []struct {
+				key, val string
+				found    bool
+			}{
+				{"A", "query", true},
+				{"B", "2", true},
+				{"D", "4", true},
+				{"E", "", false},
+			}
+			for _, test := range tests {
+				t.Run(test.key, func(t *testing.T) {
+					v, got, err := l.LookupKey(test.key)
+					if v != test.val {
+						t.Errorf("Unexpected value: got %q, expecting %q", v, test.val)
+					}
+
+					if got != test.found {
+						t.Errorf("Unexpected bool result: got %t, expecting %t", got, test.found)
+					}
+
+					if err != nil {
+						t.Errorf("Unexpected error: got %q instead of nil", err)
+					}
+				})
+			}
+
+			var c struct {
+				C bool `lookup:"C"`
+			}
+			if err := lookup.Lookup(&c, nil, l); err != nil || !c.C {
+				t.Errorf("Unexpected result for C: %t, %v", c.C, err)
+			}
+		})
+	}
+}
+
+func TestRequestLookerReset(t *testing.T) {
+	req, err := http.NewRequest(http.MethodGet, "/?A=1&B=2", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l := lookup.NewRequest(req)
+	if v, ok, _ := l.LookupKey("A"); v != "1" || !ok {
+		t.Errorf("Unexpected result: got %q/%t, expecting %q", v, ok, "1")
+	}
+
+	if req, err = http.NewRequest(http.MethodGet, "/?A=3", nil); err != nil {
+		t.Fatal(err)
+	}
+	l.Reset(req)
+	if v, ok, _ := l.LookupKey("A"); v != "3" || !ok {
+		t.Errorf("Unexpected result after Reset: got %q/%t, expecting %q", v, ok, "3")
+	}
+	if keys := l.(lookup.Enumerable).Keys(); len(keys) != 1 {
+		t.Errorf("Values from previous request are still cached: %v", keys)
+	}
+}
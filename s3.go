@@ -0,0 +1,96 @@
+package lookup
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"sync"
+
+	"golang.org/x/xerrors"
+)
+
+// S3Getter downloads an object from S3. It keeps the AWS SDK out of this package: adapt your
+// client by calling GetObject with ctx and returning the Body of its output. A missing object
+// must be reported as an error.
+type S3Getter interface {
+	GetObject(ctx context.Context, bucket, key string) (io.ReadCloser, error)
+}
+
+type s3JSONLooker struct {
+	ctx         context.Context
+	client      S3Getter
+	bucket, key string
+
+	mutex sync.Mutex
+	data  map[string]interface{}
+	err   error
+}
+
+// NewS3JSON returns a Looker that extracts data from a JSON object stored in S3. The object is
+// downloaded only once, with ctx, so its deadline and cancellation apply to the download.
+func NewS3JSON(ctx context.Context, client S3Getter, bucket, key string) Looker {
+	return &s3JSONLooker{
+		ctx:    ctx,
+		client: client,
+		bucket: bucket,
+		key:    key,
+	}
+}
+
+func (l *s3JSONLooker) LookupKey(k string) (string, bool, error) {
+	if err := l.once(); err != nil {
+		return "", false, err
+	}
+	v, ok := jsonLookup(l.data, k)
+	return v, ok, nil
+}
+
+// LookupRawJSON returns the value of k encoded as JSON.
+func (l *s3JSONLooker) LookupRawJSON(k string) (json.RawMessage, bool, error) {
+	if err := l.once(); err != nil {
+		return nil, false, err
+	}
+	return jsonRawLookup(l.data, k)
+}
+
+// Keys returns the keys in the object, which is downloaded if needed.
+func (l *s3JSONLooker) Keys() []string {
+	l.once()
+	return mapKeys(l.data)
+}
+
+// KeysErr is like Keys, but also returns the error loading the object.
+func (l *s3JSONLooker) KeysErr() ([]string, error) {
+	if err := l.once(); err != nil {
+		return nil, err
+	}
+	return mapKeys(l.data), nil
+}
+
+func (l *s3JSONLooker) once() error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if l.data == nil {
+		// If object fails to load, don't try again for the same instance:
+		l.data = make(map[string]interface{})
+		l.err = l.load()
+	}
+	return l.err
+}
+
+func (l *s3JSONLooker) load() error {
+	body, err := l.client.GetObject(l.ctx, l.bucket, l.key)
+	if err != nil {
+		return &SourceError{Source: l.source(), Err: xerrors.Errorf("cannot get: %w", err)}
+	}
+	err = decodeJSON(body, &l.data)
+	body.Close()
+	if err != nil {
+		return &SourceError{Source: l.source(), Err: xerrors.Errorf("cannot decode: %w", err)}
+	}
+	return nil
+}
+
+func (l *s3JSONLooker) source() string {
+	return "s3://" + l.bucket + "/" + l.key
+}
@@ -0,0 +1,67 @@
+package lookup_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/carloslenz/lookup"
+	"golang.org/x/xerrors"
+)
+
+type fakeS3 struct {
+	objects map[string]string
+	calls   int
+}
+
+var errNoSuchKey = errors.New("NoSuchKey")
+
+func (s *fakeS3) GetObject(ctx context.Context, bucket, key string) (io.ReadCloser, error) {
+	s.calls++
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	o, ok := s.objects[bucket+"/"+key]
+	if !ok {
+		return nil, errNoSuchKey
+	}
+	return ioutil.NopCloser(strings.NewReader(o)), nil
+}
+
+func TestS3JSON(t *testing.T) {
+	s3 := &fakeS3{objects: map[string]string{"cfg/app.json": `{"PORT": 8080}`}}
+
+	l := lookup.NewS3JSON(context.Background(), s3, "cfg", "app.json")
+	for _, k := range []string{"PORT", "PORT", "OTHER"} {
+		if _, _, err := l.LookupKey(k); err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+	}
+	v, ok, _ := l.LookupKey("PORT")
+	if v != "8080" || !ok {
+		t.Errorf("Unexpected result: got %q/%t, expecting %q/true", v, ok, "8080")
+	}
+	if s3.calls != 1 {
+		t.Errorf("Object downloaded %d times, expecting once", s3.calls)
+	}
+
+	missing := lookup.NewS3JSON(context.Background(), s3, "cfg", "missing.json")
+	if _, _, err := missing.LookupKey("PORT"); err == nil || !strings.Contains(err.Error(), "s3://cfg/missing.json") {
+		t.Errorf("Unexpected error for missing object: %v", err)
+	}
+}
+
+func TestS3JSONContext(t *testing.T) {
+	s3 := &fakeS3{objects: map[string]string{"cfg/app.json": `{"PORT": 8080}`}}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	l := lookup.NewS3JSON(ctx, s3, "cfg", "app.json")
+	_, _, err := l.LookupKey("PORT")
+	if !xerrors.Is(err, context.Canceled) {
+		t.Errorf("Unexpected error: got %v, expecting %v", err, context.Canceled)
+	}
+}
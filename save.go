@@ -0,0 +1,240 @@
+package lookup
+
+import (
+	"encoding"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/xerrors"
+)
+
+// Dump returns the values of the fields of e, a struct or pointer to struct, by the keys Lookup
+// reads, formatted so that Lookup loads them back (e.g, from NewJSONFile). Nil pointers, interface
+// fields and fields tagged ",join=" are left out, as well as keys matched by Options.Redact.
+// Lists with items containing the separator are saved as JSON arrays.
+func Dump(e interface{}) (Map, error) {
+	return Options{}.Dump(e)
+}
+
+// Dump is like the Dump function, customized by o.
+func (o Options) Dump(e interface{}) (Map, error) {
+	value := reflect.ValueOf(e)
+	for value.Kind() == reflect.Ptr && !value.IsNil() {
+		value = value.Elem()
+	}
+	if value.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("Dump needs a struct or pointer to struct, got %T", e)
+	}
+	m := make(Map)
+	if err := o.dumpStruct(value, "", m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// SaveJSON writes Dump(e) to w as an indented JSON object, sorted by key, e.g. for a starter
+// configuration file or the effective configuration, which NewJSONFile loads back.
+func SaveJSON(w io.Writer, e interface{}) error {
+	return Options{}.SaveJSON(w, e)
+}
+
+// SaveJSON is like the SaveJSON function, customized by o.
+func (o Options) SaveJSON(w io.Writer, e interface{}) error {
+	m, err := o.Dump(e)
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(m)
+}
+
+func (o *Options) dumpStruct(value reflect.Value, prefix string, m Map) error {
+	t := value.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := value.Field(i)
+		fieldType := t.Field(i)
+		tag := o.tagDefaults(findTag(fieldType.Tag, fieldType.Name))
+
+		if tag.nests(fieldType.Type) {
+			if fieldType.PkgPath != "" && !fieldType.Anonymous {
+				continue
+			}
+			if field.Kind() == reflect.Ptr {
+				if field.IsNil() {
+					continue
+				}
+				field = field.Elem()
+			}
+			if err := o.dumpStruct(field, o.nestedPrefix(prefix, fieldType, tag), m); err != nil {
+				return err
+			}
+			continue
+		}
+
+		tag = o.fieldNameKey(fieldType, tag)
+		if tag.key == notFound || tag.joined || fieldType.PkgPath != "" || unsupportedKind(field.Kind()) ||
+			field.Kind() == reflect.Interface || (field.Kind() == reflect.Ptr && field.IsNil()) {
+			continue
+		}
+		key := o.KeyPrefix + prefix + tag.key
+
+		if tag.indexed && (field.Kind() == reflect.Slice || field.Kind() == reflect.Array) {
+			sep := o.IndexSeparator
+			if sep == "" {
+				sep = DefaultSeparator
+			}
+			for j := 0; j < field.Len(); j++ {
+				k := key + sep + strconv.Itoa(o.IndexStart+j)
+				if err := o.dumpValue(m, k, field.Index(j), tag.item()); err != nil {
+					return xerrors.Errorf("field %q: %w", fieldType.Name, err)
+				}
+			}
+			continue
+		}
+		if err := o.dumpValue(m, key, field, tag); err != nil {
+			return xerrors.Errorf("field %q: %w", fieldType.Name, err)
+		}
+	}
+	return nil
+}
+
+// dumpValue stores field in m as key, unless Redact matches it.
+func (o *Options) dumpValue(m Map, key string, field reflect.Value, tag fieldTag) error {
+	if o.Redact != nil && o.Redact.MatchString(key) {
+		return nil
+	}
+	v, err := formatValue(field, tag)
+	if err != nil {
+		return err
+	}
+	m[key] = v
+	return nil
+}
+
+// formatValue is the reverse of setValue.
+func formatValue(field reflect.Value, tag fieldTag) (string, error) {
+	if tag.enc == "json" {
+		b, err := json.Marshal(field.Interface())
+		return string(b), err
+	}
+	switch v := field.Interface().(type) {
+	case *bool:
+		return strconv.FormatBool(*v), nil
+	case net.IP:
+		return v.String(), nil
+	case net.IPNet:
+		return v.String(), nil
+	case *net.IPNet:
+		return v.String(), nil
+	case os.FileMode:
+		n := uint32(v.Perm())
+		if v&os.ModeSetuid != 0 {
+			n |= 04000
+		}
+		if v&os.ModeSetgid != 0 {
+			n |= 02000
+		}
+		if v&os.ModeSticky != 0 {
+			n |= 01000
+		}
+		return fmt.Sprintf("%04o", n), nil
+	case time.Time:
+		if tag.layout != "" {
+			return v.Format(tag.layout), nil
+		}
+	case time.Duration:
+		return v.String(), nil
+	case *time.Location:
+		return v.String(), nil
+	case url.URL:
+		return v.String(), nil
+	case *url.URL:
+		return v.String(), nil
+	}
+
+	if k := field.Kind(); tag.unit == "duration" && k != reflect.Slice && k != reflect.Array {
+		return time.Duration(field.Int()).String(), nil
+	}
+
+	x := field.Interface()
+	if field.CanAddr() {
+		x = field.Addr().Interface()
+	}
+	switch m := x.(type) {
+	case encoding.TextMarshaler:
+		b, err := m.MarshalText()
+		return string(b), err
+	case json.Marshaler:
+		b, err := m.MarshalJSON()
+		return string(b), err
+	}
+
+	switch field.Kind() {
+	case reflect.String:
+		return field.String(), nil
+
+	case reflect.Slice:
+		if field.Type().Elem().Kind() == reflect.Uint8 {
+			return encodeBytes(field.Bytes(), tag), nil
+		}
+		if isNested(field.Type().Elem()) {
+			b, err := json.Marshal(field.Interface())
+			return string(b), err
+		}
+		fallthrough
+	case reflect.Array:
+		sep := tag.sep
+		if sep == "" {
+			sep = defaultSep
+		}
+		items := make([]string, field.Len())
+		quote := false
+		for i := range items {
+			item, err := formatValue(field.Index(i), tag.item())
+			if err != nil {
+				return "", xerrors.Errorf("item %d: %w", i, err)
+			}
+			items[i] = item
+			quote = quote || strings.Contains(item, sep)
+		}
+		if quote {
+			// Splitting would break items, so they are saved as a JSON array, which setList accepts.
+			b, err := json.Marshal(items)
+			return string(b), err
+		}
+		return strings.Join(items, sep), nil
+
+	case reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return fmt.Sprint(field.Interface()), nil
+
+	case reflect.Map:
+		return "", fmt.Errorf("cannot save values of type %s without enc=json", field.Type())
+	}
+	return fmt.Sprint(field.Interface()), nil
+}
+
+// encodeBytes is the reverse of decodeBytes. With enc=auto, bytes are encoded as hex, which is
+// never mistaken for base64.
+func encodeBytes(b []byte, tag fieldTag) string {
+	switch {
+	case tag.raw:
+		return string(b)
+	case tag.enc == "hex", tag.enc == "auto":
+		return hex.EncodeToString(b)
+	default:
+		return base64.RawStdEncoding.EncodeToString(b)
+	}
+}
@@ -0,0 +1,93 @@
+package lookup_test
+
+import (
+	"bytes"
+	"os"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/carloslenz/lookup"
+)
+
+func TestSaveJSON(t *testing.T) {
+	type conf struct {
+		Host    string        `lookup:"HOST"`
+		Ports   []int         `lookup:"PORTS"`
+		Timeout time.Duration `lookup:"TIMEOUT"`
+		Mode    os.FileMode   `lookup:"MODE"`
+		Key     []byte        `lookup:"KEY,enc=hex"`
+		Token   []byte        `lookup:"TOKEN"`
+		Start   time.Time     `lookup:"START"`
+		Items   []string      `lookup:"ITEM,indexed"`
+		Debug   *bool         `lookup:"DEBUG,optional"`
+		DB      struct {
+			Name     string `lookup:"NAME"`
+			Password string `lookup:"PASSWORD,optional"`
+		} `lookup:"DB"`
+	}
+	in := conf{
+		Host:    "localhost",
+		Ports:   []int{80, 443},
+		Timeout: 90 * time.Second,
+		Mode:    0644 | os.ModeSetgid,
+		Key:     []byte{0xbe, 0xef},
+		Token:   []byte("secret"),
+		Start:   time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC),
+		Items:   []string{"a", "b"},
+	}
+	in.DB.Name = "app"
+	in.DB.Password = "hunter2"
+
+	var b bytes.Buffer
+	o := lookup.Options{Redact: regexp.MustCompile("PASSWORD")}
+	if err := o.SaveJSON(&b, in); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if strings.Contains(b.String(), "hunter2") || strings.Contains(b.String(), "DEBUG") {
+		t.Errorf("Redacted and nil fields should be left out: %s", b.String())
+	}
+
+	var out conf
+	if err := lookup.Lookup(&out, nil, lookup.NewJSONReader(&b)); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	in.DB.Password = ""
+	if !reflect.DeepEqual(out, in) {
+		t.Errorf("Unexpected result: got %+v, expecting %+v", out, in)
+	}
+
+	if _, err := lookup.Dump(1); err == nil {
+		t.Error("Dump needs a struct, why no error?!")
+	}
+}
+
+func TestDumpKeyPrefix(t *testing.T) {
+	type conf struct {
+		Host  string   `lookup:"HOST"`
+		Hosts []string `lookup:"HOSTS"`
+		Items []string `lookup:"ITEM,indexed"`
+	}
+	in := conf{Host: "localhost", Hosts: []string{"a,b", "c"}, Items: []string{"x"}}
+	o := lookup.Options{KeyPrefix: "APP_"}
+	m, err := o.Dump(in)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if m["APP_HOST"] != "localhost" || m["APP_ITEM_0"] != "x" {
+		t.Errorf("Unexpected dump: %q", m)
+	}
+	if m["APP_HOSTS"] != `["a,b","c"]` {
+		t.Errorf("Unexpected list: got %q, expecting a JSON array", m["APP_HOSTS"])
+	}
+
+	var out conf
+	if err := o.Lookup(&out, nil, m); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if !reflect.DeepEqual(out, in) {
+		t.Errorf("Unexpected result: got %+v, expecting %+v", out, in)
+	}
+}
@@ -0,0 +1,56 @@
+package lookup
+
+// Session groups the reports of several Lookup calls that make up one load, e.g. one per
+// subsystem, so reporters see keys as "group/key".
+type Session struct {
+	// Options customizes every Lookup in the session.
+	Options Options
+
+	r Reporter
+}
+
+// NewSession creates a Session reporting to r, which can be nil.
+func NewSession(r Reporter) *Session {
+	if r == nil {
+		r = discard
+	}
+	return &Session{r: r}
+}
+
+// Lookup is like Options.Lookup, but keys are reported prefixed by group and "/".
+func (s *Session) Lookup(group string, e interface{}, seq ...Looker) error {
+	return s.Options.Lookup(e, groupReporter{r: s.r, prefix: group + "/"}, seq...)
+}
+
+type groupReporter struct {
+	r      Reporter
+	prefix string
+}
+
+func (r groupReporter) Report(key string, e interface{}) {
+	r.r.Report(r.prefix+key, e)
+}
+
+func (r groupReporter) ReportMissing(key string) {
+	reportMissing(r.r, r.prefix+key)
+}
+
+func (r groupReporter) ReportDeprecated(key, message string) {
+	reportDeprecated(r.r, r.prefix+key, message)
+}
+
+func (r groupReporter) ReportAlias(key, alias string) {
+	reportAlias(r.r, r.prefix+key, alias)
+}
+
+func (r groupReporter) ReportSource(key, source string) {
+	reportSource(r.r, r.prefix+key, source)
+}
+
+func (r groupReporter) ReportShadowed(key, source, value, winner, winning string) {
+	reportShadowed(r.r, r.prefix+key, source, value, winner, winning)
+}
+
+func (r groupReporter) ReportError(key string, err error) {
+	reportError(r.r, r.prefix+key, err)
+}
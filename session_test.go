@@ -0,0 +1,35 @@
+package lookup_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/carloslenz/lookup"
+)
+
+func TestSession(t *testing.T) {
+	var db struct {
+		Host string `lookup:"HOST"`
+		Port int    `lookup:"PORT,optional"`
+	}
+	var http struct {
+		Port int `lookup:"PORT"`
+	}
+	e := &missingEntries{}
+	sess := lookup.NewSession(e)
+	if err := sess.Lookup("db", &db, lookup.Map{"HOST": "localhost"}); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if err := sess.Lookup("http", &http, lookup.Map{"PORT": "80"}); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if db.Host != "localhost" || http.Port != 80 {
+		t.Errorf("Unexpected result: %#v, %#v", db, http)
+	}
+	if expected := (entries{"db/HOST", "localhost", "http/PORT", "80"}); !reflect.DeepEqual(e.entries, expected) {
+		t.Errorf("Unexpected reports: %#v, expecting %#v", e.entries, expected)
+	}
+	if expected := []string{"db/PORT"}; !reflect.DeepEqual(e.missing, expected) {
+		t.Errorf("Unexpected missing: %#v, expecting %#v", e.missing, expected)
+	}
+}
@@ -0,0 +1,538 @@
+package lookup
+
+import (
+	"encoding"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"math"
+	"net"
+	"net/url"
+	"os"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/xerrors"
+)
+
+const defaultSep = ","
+
+func (o *Options) setField(field reflect.Value, v, fieldKey string, tag fieldTag, r Reporter) error {
+	rep, err := o.setValue(field, v, tag)
+	if err != nil {
+		return err
+	}
+	r.Report(fieldKey, rep)
+	return nil
+}
+
+// setValue parses v into field and returns what should be reported.
+func (o *Options) setValue(field reflect.Value, v string, tag fieldTag) (interface{}, error) {
+	if tag.parser != "" {
+		return setParsed(field, v, tag.parser)
+	}
+	if tag.enc == "json" {
+		return setJSON(field, v)
+	}
+
+	switch field.Interface().(type) {
+	case *bool:
+		var b bool
+		if err := scanBool(v, &b); err != nil {
+			return nil, err
+		}
+		field.Set(reflect.ValueOf(&b))
+		return b, nil
+
+	case json.RawMessage:
+		b := []byte(v)
+		if !json.Valid(b) {
+			var err error
+			if b, err = json.Marshal(v); err != nil {
+				return nil, err
+			}
+		}
+		field.SetBytes(b)
+		return fmt.Sprintf("(%d bytes)", len(b)), nil
+
+	case net.IP:
+		ip := net.ParseIP(v)
+		if ip == nil {
+			return nil, fmt.Errorf("invalid IP address %q", v)
+		}
+		field.Set(reflect.ValueOf(ip))
+		return ip.String(), nil
+
+	case net.IPNet, *net.IPNet:
+		_, n, err := net.ParseCIDR(v)
+		if err != nil {
+			return nil, err
+		}
+		setValueOrPointer(field, reflect.ValueOf(n))
+		return n.String(), nil
+
+	case os.FileMode:
+		base := 8
+		if len(v) > 1 && v[0] == '0' && strings.ContainsAny(v[1:2], "xXoObB") {
+			base = 0
+		}
+		n, err := strconv.ParseUint(v, base, 32)
+		if err != nil {
+			return nil, err
+		}
+		if n > 07777 {
+			return nil, fmt.Errorf("file mode %#o has more than permission, setuid, setgid and sticky bits", n)
+		}
+		m := os.FileMode(n & 0777)
+		if n&04000 != 0 {
+			m |= os.ModeSetuid
+		}
+		if n&02000 != 0 {
+			m |= os.ModeSetgid
+		}
+		if n&01000 != 0 {
+			m |= os.ModeSticky
+		}
+		field.Set(reflect.ValueOf(m))
+		return m.String(), nil
+
+	case time.Duration:
+		d, err := o.parseDuration(v)
+		if err != nil {
+			return nil, err
+		}
+		field.SetInt(int64(d))
+		return d.String(), nil
+
+	case time.Time:
+		if tag.layout == "" {
+			break
+		}
+		t, err := time.Parse(tag.layout, v)
+		if err != nil {
+			return nil, err
+		}
+		field.Set(reflect.ValueOf(t))
+		return t.Format(tag.layout), nil
+
+	case *time.Location:
+		loc, err := time.LoadLocation(v)
+		if err != nil {
+			return nil, xerrors.Errorf("unknown time zone %q: %w", v, err)
+		}
+		field.Set(reflect.ValueOf(loc))
+		return loc.String(), nil
+
+	case url.URL, *url.URL:
+		u, err := url.Parse(v)
+		if err != nil {
+			return nil, err
+		}
+		setValueOrPointer(field, reflect.ValueOf(u))
+		return u.String(), nil
+
+	case HostPort, *HostPort:
+		h, err := parseHostPort(v)
+		if err != nil {
+			return nil, err
+		}
+		setValueOrPointer(field, reflect.ValueOf(&h))
+		return h.String(), nil
+	}
+
+	if k := field.Kind(); tag.unit != "" && k != reflect.Slice && k != reflect.Array {
+		return o.setUnit(field, v, tag.unit)
+	}
+
+	if field.CanAddr() {
+		switch u := field.Addr().Interface().(type) {
+		case json.Unmarshaler:
+			if err := unmarshalJSON(u, v); err != nil {
+				return nil, err
+			}
+			return field.Interface(), nil
+		case encoding.TextUnmarshaler:
+			if err := u.UnmarshalText([]byte(v)); err != nil {
+				return nil, err
+			}
+			return field.Interface(), nil
+		case flag.Value:
+			if err := u.Set(v); err != nil {
+				return nil, err
+			}
+			return u.String(), nil
+		}
+	}
+
+	// Kinds instead of types, so named types like "type Level string" work too.
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(v)
+		return v, nil
+
+	case reflect.Slice:
+		if field.Type().Elem().Kind() == reflect.Uint8 {
+			b, err := decodeBytes(v, tag)
+			if err != nil {
+				return nil, err
+			}
+			field.SetBytes(b)
+			return b, nil
+		}
+		if isNested(field.Type().Elem()) {
+			if err := json.Unmarshal([]byte(v), field.Addr().Interface()); err != nil {
+				return nil, err
+			}
+			return fmt.Sprintf("(%d items)", field.Len()), nil
+		}
+		fallthrough
+	case reflect.Array:
+		if err := o.setList(field, v, tag); err != nil {
+			return nil, err
+		}
+		return field.Interface(), nil
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		v = o.normalizeNumber(v)
+	}
+
+	if !field.CanAddr() {
+		return nil, fmt.Errorf("value of type %s is not addressable", field.Type())
+	}
+	ptr := field.Addr().Interface()
+	if _, ok := ptr.(fmt.Scanner); ok {
+		if err := scanAll(v, ptr); err != nil {
+			return nil, err
+		}
+		return field.Interface(), nil
+	}
+
+	switch field.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		// Base 0 accepts Go literals like 0xFF, 0o755, 0b1010 and 1_000. Surrounding spaces are
+		// accepted, like with fmt.Sscanln.
+		n, err := strconv.ParseInt(strings.TrimSpace(v), 0, field.Type().Bits())
+		if err != nil {
+			return nil, err
+		}
+		field.SetInt(n)
+		return field.Interface(), nil
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(strings.TrimSpace(v), 0, field.Type().Bits())
+		if err != nil {
+			return nil, err
+		}
+		field.SetUint(n)
+		return field.Interface(), nil
+	}
+
+	if field.Kind() == reflect.Bool {
+		var b bool
+		if err := scanBool(v, &b); err != nil {
+			return nil, err
+		}
+		field.SetBool(b)
+		return field.Interface(), nil
+	}
+
+	if err := sscanln(v, ptr); err != nil {
+		return nil, err
+	}
+	return field.Interface(), nil
+}
+
+// setJSON decodes v into field, for tag option enc=json, and returns a summary to report.
+func setJSON(field reflect.Value, v string) (interface{}, error) {
+	if !field.CanAddr() {
+		return nil, fmt.Errorf("value of type %s is not addressable", field.Type())
+	}
+	if err := json.Unmarshal([]byte(v), field.Addr().Interface()); err != nil {
+		return nil, xerrors.Errorf("invalid JSON: %w", err)
+	}
+	switch field.Kind() {
+	case reflect.Slice, reflect.Array:
+		return fmt.Sprintf("(%d items)", field.Len()), nil
+	case reflect.Map:
+		return fmt.Sprintf("(%d keys)", field.Len()), nil
+	}
+	return field.Interface(), nil
+}
+
+// unmarshalJSON hands v to u as is if it is valid JSON (e.g, objects from NewJSONFile), otherwise
+// or if u rejects it, as a JSON string.
+func unmarshalJSON(u json.Unmarshaler, v string) error {
+	if json.Valid([]byte(v)) && u.UnmarshalJSON([]byte(v)) == nil {
+		return nil
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return u.UnmarshalJSON(b)
+}
+
+var hexString = regexp.MustCompile(`^(?:[0-9a-fA-F]{2})+$`)
+
+// decodeBytes decodes v according to tag options raw and enc.
+func decodeBytes(v string, tag fieldTag) ([]byte, error) {
+	if tag.raw {
+		return []byte(v), nil
+	}
+	switch tag.enc {
+	case "", "base64":
+		return base64.RawStdEncoding.DecodeString(v)
+	case "hex":
+		return hex.DecodeString(v)
+	case "auto":
+		if hexString.MatchString(v) {
+			return hex.DecodeString(v)
+		}
+		return base64.RawStdEncoding.DecodeString(v)
+	default:
+		return nil, fmt.Errorf("unknown encoding %q", tag.enc)
+	}
+}
+
+// setPercent parses v into a float field, dividing it by 100 if it ends with "%" (e.g, "75%" is
+// 0.75). Other values are plain floats.
+func (o *Options) setPercent(field reflect.Value, v string) (interface{}, error) {
+	if k := field.Kind(); k != reflect.Float32 && k != reflect.Float64 {
+		return nil, fmt.Errorf("unit=percent needs a float field, not %s", field.Type())
+	}
+	s := strings.TrimSpace(v)
+	percent := strings.HasSuffix(s, "%")
+	if percent {
+		s = strings.TrimSpace(s[:len(s)-1])
+	}
+	f, err := strconv.ParseFloat(o.normalizeNumber(s), field.Type().Bits())
+	if err != nil {
+		return nil, fmt.Errorf("invalid percentage or ratio %q", v)
+	}
+	if percent {
+		f /= 100
+	}
+	field.SetFloat(f)
+	return field.Interface(), nil
+}
+
+// byteUnits are the multipliers of suffixes accepted by unit=bytes, in lower case.
+var byteUnits = map[string]uint64{
+	"": 1, "b": 1,
+	"k": 1e3, "kb": 1e3, "kib": 1 << 10,
+	"m": 1e6, "mb": 1e6, "mib": 1 << 20,
+	"g": 1e9, "gb": 1e9, "gib": 1 << 30,
+	"t": 1e12, "tb": 1e12, "tib": 1 << 40,
+	"p": 1e15, "pb": 1e15, "pib": 1 << 50,
+	"e": 1e18, "eb": 1e18, "eib": 1 << 60,
+}
+
+// setUnit parses v into an integer field according to tag option unit: "bytes" accepts SI and
+// binary suffixes (e.g, "10MB", "512KiB") and "duration" is like time.ParseDuration. "percent" is
+// for float fields (see setPercent).
+func (o *Options) setUnit(field reflect.Value, v, unit string) (interface{}, error) {
+	if unit == "percent" {
+		return o.setPercent(field, v)
+	}
+	var n uint64
+	var neg bool
+	switch unit {
+	case "bytes":
+		var err error
+		if n, err = parseBytes(v); err != nil {
+			return nil, err
+		}
+	case "duration":
+		d, err := o.parseDuration(v)
+		if err != nil {
+			return nil, err
+		}
+		if neg = d < 0; neg {
+			d = -d
+		}
+		n = uint64(d)
+	default:
+		return nil, fmt.Errorf("unknown unit %q", unit)
+	}
+
+	switch field.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i := int64(n)
+		if neg {
+			i = -i
+		}
+		if n > math.MaxInt64 || field.OverflowInt(i) {
+			return nil, fmt.Errorf("%q overflows %s", v, field.Type())
+		}
+		field.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if neg || field.OverflowUint(n) {
+			return nil, fmt.Errorf("%q overflows %s", v, field.Type())
+		}
+		field.SetUint(n)
+	default:
+		return nil, fmt.Errorf("unit=%s needs an integer field, not %s", unit, field.Type())
+	}
+	return field.Interface(), nil
+}
+
+// parseDuration accepts the keys of DurationKeywords, integers as nanoseconds (e.g, "-1") and
+// whatever time.ParseDuration accepts.
+func (o *Options) parseDuration(v string) (time.Duration, error) {
+	if d, ok := o.DurationKeywords[v]; ok {
+		return d, nil
+	}
+	if n, err := strconv.ParseInt(v, 10, 64); err == nil {
+		return time.Duration(n), nil
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil && len(o.DurationKeywords) > 0 {
+		keywords := mapKeys(o.DurationKeywords)
+		for i, k := range keywords {
+			keywords[i] = strconv.Quote(k)
+		}
+		return 0, fmt.Errorf("invalid duration %q: expecting %s or a valid duration",
+			v, strings.Join(keywords, ", "))
+	}
+	return d, err
+}
+
+// parseBytes parses a size like "1.5GB" into a number of bytes.
+func parseBytes(v string) (uint64, error) {
+	i := strings.IndexFunc(v, func(r rune) bool {
+		return (r < '0' || r > '9') && r != '.'
+	})
+	if i < 0 {
+		i = len(v)
+	}
+	num, suffix := v[:i], strings.TrimSpace(v[i:])
+	mult, ok := byteUnits[strings.ToLower(suffix)]
+	if !ok {
+		return 0, fmt.Errorf("unknown size suffix %q", suffix)
+	}
+	if !strings.Contains(num, ".") {
+		n, err := strconv.ParseUint(num, 10, 64)
+		if err != nil {
+			return 0, err
+		}
+		if n > math.MaxUint64/mult {
+			return 0, fmt.Errorf("size %q is too large", v)
+		}
+		return n * mult, nil
+	}
+	f, err := strconv.ParseFloat(num, 64)
+	if err != nil {
+		return 0, err
+	}
+	f *= float64(mult)
+	if f != math.Trunc(f) {
+		return 0, fmt.Errorf("size %q is not a whole number of bytes", v)
+	}
+	if f >= math.MaxUint64 {
+		return 0, fmt.Errorf("size %q is too large", v)
+	}
+	return uint64(f), nil
+}
+
+// normalizeNumber removes ThousandsSep and replaces DecimalMark with ".".
+func (o *Options) normalizeNumber(v string) string {
+	if o.ThousandsSep != "" {
+		v = strings.Replace(v, o.ThousandsSep, "", -1)
+	}
+	if o.DecimalMark != "" {
+		v = strings.Replace(v, o.DecimalMark, ".", -1)
+	}
+	return v
+}
+
+// setValueOrPointer sets field to ptr or to what it points to, according to field type.
+func setValueOrPointer(field, ptr reflect.Value) {
+	if field.Kind() == reflect.Ptr {
+		field.Set(ptr)
+	} else {
+		field.Set(ptr.Elem())
+	}
+}
+
+// valueTypes are structs that setValue handles, instead of being nested.
+var valueTypes = map[reflect.Type]bool{
+	reflect.TypeOf(net.IPNet{}):     true,
+	reflect.TypeOf(url.URL{}):       true,
+	reflect.TypeOf(time.Location{}): true,
+	reflect.TypeOf(HostPort{}):      true,
+}
+
+// setList splits v, unless it is a JSON array, and parses each item into an element of field,
+// which is an array or slice.
+func (o *Options) setList(field reflect.Value, v string, tag fieldTag) error {
+	if items, ok := jsonItems(v); ok {
+		return o.setItems(field, items, tag)
+	}
+	sep := tag.sep
+	if sep == "" {
+		sep = defaultSep
+	}
+	var items []string
+	if v != "" {
+		items = strings.Split(v, sep)
+	}
+	return o.setItems(field, items, tag)
+}
+
+// setItems parses each of items into an element of field, which is an array or slice with tag.
+func (o *Options) setItems(field reflect.Value, items []string, tag fieldTag) error {
+	if field.Kind() == reflect.Array {
+		if len(items) != field.Len() {
+			return fmt.Errorf("expected %d values, got %d", field.Len(), len(items))
+		}
+	} else {
+		field.Set(reflect.MakeSlice(field.Type(), len(items), len(items)))
+	}
+
+	for i, item := range items {
+		if _, err := o.setValue(field.Index(i), item, tag.item()); err != nil {
+			return xerrors.Errorf("item %d: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// scanBool is like sscanln but empty values are false (e.g, "-verbose=" for NewArgs).
+func scanBool(v string, b *bool) error {
+	if v == "" {
+		*b = false
+		return nil
+	}
+	return sscanln(v, b)
+}
+
+func sscanln(v string, ptr interface{}) error {
+	n, err := fmt.Sscanln(v+"\n", ptr)
+	if err != nil {
+		return err
+	}
+	if n != 1 {
+		return errors.New("nothing to read")
+	}
+	return nil
+}
+
+// scanAll hands the whole of v, spaces included, to a fmt.Scanner, which must consume it entirely.
+func scanAll(v string, ptr interface{}) error {
+	rd := strings.NewReader(v)
+	if _, err := fmt.Fscan(rd, ptr); err != nil {
+		return err
+	}
+	if rd.Len() > 0 {
+		return fmt.Errorf("unexpected %q after value", v[len(v)-rd.Len():])
+	}
+	return nil
+}
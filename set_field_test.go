@@ -0,0 +1,708 @@
+package lookup_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net"
+	"net/url"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/xerrors"
+
+	"github.com/carloslenz/lookup"
+)
+
+func TestLookupArray(t *testing.T) {
+	type conf struct {
+		RGB     [3]int   `lookup:"RGB"`
+		Version [3]int   `lookup:"VERSION,optional,sep=."`
+		Hosts   []string `lookup:"HOSTS,optional"`
+	}
+	tests := []struct {
+		name string
+		m    lookup.Map
+		ok   bool
+	}{
+		{"exact", lookup.Map{"RGB": "1,2,3", "VERSION": "1.12.5", "HOSTS": "a,b"}, true},
+		{"too few", lookup.Map{"RGB": "1,2"}, false},
+		{"too many", lookup.Map{"RGB": "1,2,3,4"}, false},
+		{"bad item", lookup.Map{"RGB": "1,x,3"}, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var c conf
+			err := lookup.Lookup(&c, nil, test.m)
+			if !test.ok {
+				if err == nil {
+					t.Errorf("Invalid array, why no error?! conf = %#v", c)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %s", err)
+			}
+			expected := conf{RGB: [3]int{1, 2, 3}, Version: [3]int{1, 12, 5}, Hosts: []string{"a", "b"}}
+			if !reflect.DeepEqual(c, expected) {
+				t.Errorf("Unexpected result: %#v, expecting %#v", c, expected)
+			}
+		})
+	}
+}
+
+func TestLookupWrapsParseError(t *testing.T) {
+	var c struct {
+		N []int64 `lookup:"N"`
+	}
+	err := lookup.Lookup(&c, nil, lookup.Map{"N": "1,99999999999999999999"})
+	if !xerrors.Is(err, strconv.ErrRange) {
+		t.Errorf("Error should wrap strconv.ErrRange, got %v", err)
+	}
+}
+
+func TestLookupNetTypes(t *testing.T) {
+	type conf struct {
+		IP      net.IP     `lookup:"IP"`
+		Network *net.IPNet `lookup:"NETWORK"`
+		Subnet  net.IPNet  `lookup:"SUBNET"`
+		URL     url.URL    `lookup:"URL"`
+		Proxy   *url.URL   `lookup:"PROXY"`
+	}
+	valid := lookup.Map{
+		"IP":      "10.0.0.1",
+		"NETWORK": "10.0.0.0/8",
+		"SUBNET":  "192.168.0.0/16",
+		"URL":     "https://example.com/path",
+		"PROXY":   "http://proxy:3128",
+	}
+
+	var c conf
+	var e entries
+	if err := lookup.Lookup(&c, &e, valid); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	expectedReports := entries{
+		"IP", "10.0.0.1",
+		"NETWORK", "10.0.0.0/8",
+		"SUBNET", "192.168.0.0/16",
+		"URL", "https://example.com/path",
+		"PROXY", "http://proxy:3128",
+	}
+	if !reflect.DeepEqual(e, expectedReports) {
+		t.Errorf("Unexpected reports: %#v, expecting %#v", e, expectedReports)
+	}
+	if !c.IP.Equal(net.IPv4(10, 0, 0, 1)) || c.URL.Host != "example.com" || c.Proxy.Port() != "3128" {
+		t.Errorf("Unexpected result: %#v", c)
+	}
+
+	for k, v := range map[string]string{"IP": "10.0.0", "NETWORK": "10.0.0.0/33", "URL": "://example.com"} {
+		t.Run(k, func(t *testing.T) {
+			m := lookup.Map{}
+			for k, v := range valid {
+				m[k] = v
+			}
+			m[k] = v
+			err := lookup.Lookup(&c, nil, m)
+			if err == nil || !strings.Contains(err.Error(), strconv.Quote(v)) {
+				t.Errorf("Error should show invalid input %q, got %v", v, err)
+			}
+		})
+	}
+}
+
+func TestLookupHostPort(t *testing.T) {
+	type conf struct {
+		Listen lookup.HostPort  `lookup:"LISTEN"`
+		Admin  *lookup.HostPort `lookup:"ADMIN,optional"`
+	}
+	for v, expected := range map[string]lookup.HostPort{
+		"0.0.0.0:8080": {"0.0.0.0", 8080},
+		":8080":        {"", 8080},
+		"[::1]:443":    {"::1", 443},
+	} {
+		var c conf
+		var e entries
+		if err := lookup.Lookup(&c, &e, lookup.Map{"LISTEN": v, "ADMIN": v}); err != nil {
+			t.Fatalf("Unexpected error for %q: %s", v, err)
+		}
+		if c.Listen != expected || c.Admin == nil || *c.Admin != expected {
+			t.Errorf("Unexpected result for %q: %#v", v, c)
+		}
+		if expected := (entries{"LISTEN", v, "ADMIN", v}); !reflect.DeepEqual(e, expected) {
+			t.Errorf("Unexpected reports: %#v, expecting %#v", e, expected)
+		}
+	}
+
+	for _, v := range []string{"localhost", "localhost:", "localhost:http", "localhost:65536"} {
+		var c conf
+		if err := lookup.Lookup(&c, nil, lookup.Map{"LISTEN": v}); err == nil {
+			t.Errorf("Invalid address %q, why no error?!", v)
+		}
+	}
+}
+
+func TestLookupLocation(t *testing.T) {
+	var c struct {
+		TZ *time.Location `lookup:"TZ"`
+	}
+	var e entries
+	if err := lookup.Lookup(&c, &e, lookup.Map{"TZ": "UTC"}); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if c.TZ != time.UTC {
+		t.Errorf("Unexpected location: %v", c.TZ)
+	}
+	if expected := (entries{"TZ", "UTC"}); !reflect.DeepEqual(e, expected) {
+		t.Errorf("Unexpected reports: %#v, expecting %#v", e, expected)
+	}
+
+	err := lookup.Lookup(&c, nil, lookup.Map{"TZ": "Mars/Olympus_Mons"})
+	if err == nil || !strings.Contains(err.Error(), "unknown time zone") {
+		t.Errorf("Unexpected error for unknown zone: %v", err)
+	}
+}
+
+func TestLookupNumberFormat(t *testing.T) {
+	type conf struct {
+		Price float64 `lookup:"PRICE"`
+		Count int     `lookup:"COUNT"`
+	}
+	tests := []struct {
+		name string
+		o    lookup.Options
+		m    lookup.Map
+	}{
+		{"US", lookup.Options{ThousandsSep: ","}, lookup.Map{"PRICE": "1,234.56", "COUNT": "1,000"}},
+		{"EU", lookup.Options{ThousandsSep: ".", DecimalMark: ","}, lookup.Map{"PRICE": "1.234,56", "COUNT": "1.000"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var c conf
+			if err := lookup.Lookup(&c, nil, test.m); err == nil {
+				t.Errorf("Strict parsing should fail, yet conf = %#v", c)
+			}
+			if err := test.o.Lookup(&c, nil, test.m); err != nil {
+				t.Fatalf("Unexpected error: %s", err)
+			}
+			if expected := (conf{Price: 1234.56, Count: 1000}); c != expected {
+				t.Errorf("Unexpected result: %#v, expecting %#v", c, expected)
+			}
+		})
+	}
+}
+
+func TestLookupRawBytes(t *testing.T) {
+	var c struct {
+		Token []byte `lookup:"TOKEN,raw"`
+		Key   []byte `lookup:"KEY"`
+	}
+	var e entries
+	if err := lookup.Lookup(&c, &e, lookup.Map{"TOKEN": "not base64!", "KEY": "c2VjcmV0"}); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if string(c.Token) != "not base64!" || string(c.Key) != "secret" {
+		t.Errorf("Unexpected result: %q, %q", c.Token, c.Key)
+	}
+	expected := entries{"TOKEN", fmt.Sprint([]byte("not base64!")), "KEY", fmt.Sprint([]byte("secret"))}
+	if !reflect.DeepEqual(e, expected) {
+		t.Errorf("Unexpected reports: %#v, expecting %#v", e, expected)
+	}
+}
+
+func TestLookupIntegerLiterals(t *testing.T) {
+	tests := []struct {
+		in       string
+		expected int64
+	}{
+		{"42", 42},
+		{"-7", -7},
+		{"0xFF", 255},
+		{"0o755", 0755},
+		{"0755", 0755},
+		{"0b1010", 10},
+		{"1_000", 1000},
+		{" 8080", 8080},
+		{"8080 ", 8080},
+	}
+	for _, test := range tests {
+		t.Run(test.in, func(t *testing.T) {
+			var c struct {
+				N int64 `lookup:"N"`
+			}
+			var e entries
+			if err := lookup.Lookup(&c, &e, lookup.Map{"N": test.in}); err != nil {
+				t.Fatalf("Unexpected error: %s", err)
+			}
+			if c.N != test.expected {
+				t.Errorf("Unexpected result: got %d, expecting %d", c.N, test.expected)
+			}
+			if expected := (entries{"N", fmt.Sprint(test.expected)}); !reflect.DeepEqual(e, expected) {
+				t.Errorf("Unexpected reports: %#v, expecting %#v", e, expected)
+			}
+		})
+	}
+
+	var u struct {
+		N uint16 `lookup:"N"`
+	}
+	if err := lookup.Lookup(&u, nil, lookup.Map{"N": "0xFFFF"}); err != nil || u.N != 0xFFFF {
+		t.Errorf("Unexpected result: %d, %v", u.N, err)
+	}
+	if err := lookup.Lookup(&u, nil, lookup.Map{"N": " 8080"}); err != nil || u.N != 8080 {
+		t.Errorf("Unexpected result: %d, %v", u.N, err)
+	}
+
+	var small struct {
+		N int8 `lookup:"N"`
+	}
+	if err := lookup.Lookup(&small, nil, lookup.Map{"N": "0x100"}); !xerrors.Is(err, strconv.ErrRange) {
+		t.Errorf("Error should wrap strconv.ErrRange, got %v", err)
+	}
+}
+
+func TestLookupFileMode(t *testing.T) {
+	tests := []struct {
+		in       string
+		expected os.FileMode
+		report   string
+	}{
+		{"0644", 0644, "-rw-r--r--"},
+		{"755", 0755, "-rwxr-xr-x"},
+		{"0o600", 0600, "-rw-------"},
+		{"1777", os.ModeSticky | 0777, "trwxrwxrwx"},
+	}
+	for _, test := range tests {
+		t.Run(test.in, func(t *testing.T) {
+			var c struct {
+				Mode os.FileMode `lookup:"MODE"`
+			}
+			var e entries
+			if err := lookup.Lookup(&c, &e, lookup.Map{"MODE": test.in}); err != nil {
+				t.Fatalf("Unexpected error: %s", err)
+			}
+			if c.Mode != test.expected {
+				t.Errorf("Unexpected result: got %v, expecting %v", c.Mode, test.expected)
+			}
+			if expected := (entries{"MODE", test.report}); !reflect.DeepEqual(e, expected) {
+				t.Errorf("Unexpected reports: %#v, expecting %#v", e, expected)
+			}
+		})
+	}
+
+	for _, in := range []string{"10000", "0x1FFFF", "rw"} {
+		var c struct {
+			Mode os.FileMode `lookup:"MODE"`
+		}
+		if err := lookup.Lookup(&c, nil, lookup.Map{"MODE": in}); err == nil {
+			t.Errorf("Mode %q is invalid, why no error?!", in)
+		}
+	}
+}
+
+func TestLookupBytesEncoding(t *testing.T) {
+	type conf struct {
+		Digest []byte `lookup:"DIGEST,enc=hex"`
+		Auto   []byte `lookup:"AUTO,enc=auto"`
+	}
+	tests := []struct {
+		digest, auto, expectedAuto string
+	}{
+		{"cafe", "CAFE", "\xca\xfe"},
+		{"cafe", "c2VjcmV0", "secret"},
+		{"cafe", "abc", "i\xb7"},
+	}
+	for _, test := range tests {
+		t.Run(test.auto, func(t *testing.T) {
+			var c conf
+			if err := lookup.Lookup(&c, nil, lookup.Map{"DIGEST": test.digest, "AUTO": test.auto}); err != nil {
+				t.Fatalf("Unexpected error: %s", err)
+			}
+			if string(c.Digest) != "\xca\xfe" || string(c.Auto) != test.expectedAuto {
+				t.Errorf("Unexpected result: %q, %q", c.Digest, c.Auto)
+			}
+		})
+	}
+
+	var bad struct {
+		B []byte `lookup:"B,enc=rot13"`
+	}
+	if err := lookup.Lookup(&bad, nil, lookup.Map{"B": "x"}); err == nil {
+		t.Error("Encoding is unknown, why no error?!")
+	}
+}
+
+func TestLookupJSONEncoding(t *testing.T) {
+	type conf struct {
+		Servers []string       `lookup:"SERVERS,enc=json"`
+		Limits  map[string]int `lookup:"LIMITS,enc=json"`
+		Owner   struct {
+			Name string `json:"name"`
+		} `lookup:"OWNER,enc=json"`
+	}
+	m := lookup.Map{
+		"SERVERS": `["a,1","b"]`,
+		"LIMITS":  `{"cpu":2,"memory":512}`,
+		"OWNER":   `{"name":"ops"}`,
+	}
+	var c conf
+	var e entries
+	if err := lookup.Lookup(&c, &e, m); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if len(c.Servers) != 2 || c.Servers[0] != "a,1" || c.Limits["memory"] != 512 || c.Owner.Name != "ops" {
+		t.Errorf("Unexpected result: %#v", c)
+	}
+	expected := entries{"SERVERS", "(2 items)", "LIMITS", "(2 keys)", "OWNER", "{ops}"}
+	if !reflect.DeepEqual(e, expected) {
+		t.Errorf("Unexpected reports: %#v, expecting %#v", e, expected)
+	}
+
+	m["LIMITS"] = `{"cpu":}`
+	err := lookup.Lookup(&c, nil, m)
+	if err == nil || !strings.Contains(err.Error(), "invalid JSON") || !strings.Contains(err.Error(), `"Limits"`) {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
+
+func TestLookupUnit(t *testing.T) {
+	type conf struct {
+		Size    uint64 `lookup:"SIZE,unit=bytes"`
+		Limit   int32  `lookup:"LIMIT,optional,unit=bytes"`
+		Timeout int64  `lookup:"TIMEOUT,optional,unit=duration"`
+	}
+	tests := []struct {
+		size     string
+		expected uint64
+	}{
+		{"512", 512},
+		{"10MB", 10000000},
+		{"512KiB", 524288},
+		{"1.5gib", 1610612736},
+		{"2 GB", 2000000000},
+	}
+	for _, test := range tests {
+		t.Run(test.size, func(t *testing.T) {
+			var c conf
+			m := lookup.Map{"SIZE": test.size, "TIMEOUT": "1m30s"}
+			if err := lookup.Lookup(&c, nil, m); err != nil {
+				t.Fatalf("Unexpected error: %s", err)
+			}
+			if c.Size != test.expected || c.Timeout != int64(90*time.Second) {
+				t.Errorf("Unexpected result: %#v", c)
+			}
+		})
+	}
+
+	for _, m := range []lookup.Map{
+		{"SIZE": "10XB"},
+		{"SIZE": "1.1B"},
+		{"SIZE": "1", "LIMIT": "2GiB"},
+		{"SIZE": "1", "TIMEOUT": "ten"},
+	} {
+		var c conf
+		if err := lookup.Lookup(&c, nil, m); err == nil {
+			t.Errorf("Invalid values %v, why no error?!", m)
+		}
+	}
+}
+
+func TestLookupPercent(t *testing.T) {
+	type conf struct {
+		Rate float64 `lookup:"RATE,unit=percent"`
+	}
+	for v, expected := range map[string]float64{"75%": 0.75, "0.1": 0.1, "12.5 %": 0.125, "100%": 1} {
+		var c conf
+		if err := lookup.Lookup(&c, nil, lookup.Map{"RATE": v}); err != nil {
+			t.Fatalf("Unexpected error for %q: %s", v, err)
+		}
+		if c.Rate != expected {
+			t.Errorf("Unexpected result for %q: got %v, expecting %v", v, c.Rate, expected)
+		}
+	}
+
+	for _, v := range []string{"abc%", "%", "75%%"} {
+		var c conf
+		if err := lookup.Lookup(&c, nil, lookup.Map{"RATE": v}); err == nil {
+			t.Errorf("Invalid percentage %q, why no error?!", v)
+		}
+	}
+	var d struct {
+		Rate int `lookup:"RATE,unit=percent"`
+	}
+	if err := lookup.Lookup(&d, nil, lookup.Map{"RATE": "75%"}); err == nil {
+		t.Error("unit=percent needs a float field, why no error?!")
+	}
+}
+
+type level struct {
+	name     string
+	priority int
+}
+
+func (l *level) UnmarshalJSON(b []byte) error {
+	var obj struct {
+		Name     string `json:"name"`
+		Priority int    `json:"priority"`
+	}
+	if err := json.Unmarshal(b, &obj.Name); err == nil {
+		l.name = obj.Name
+		return nil
+	}
+	if err := json.Unmarshal(b, &obj); err != nil {
+		return err
+	}
+	l.name, l.priority = obj.Name, obj.Priority
+	return nil
+}
+
+func TestLookupUnmarshalers(t *testing.T) {
+	type conf struct {
+		Level level     `lookup:"LEVEL"`
+		Start time.Time `lookup:"START,optional"`
+	}
+	var c conf
+	if err := lookup.Lookup(&c, nil, lookup.Map{"LEVEL": "debug", "START": "2019-06-01T10:00:00Z"}); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	start := time.Date(2019, 6, 1, 10, 0, 0, 0, time.UTC)
+	if c.Level != (level{name: "debug"}) || !c.Start.Equal(start) {
+		t.Errorf("Unexpected result: %#v", c)
+	}
+
+	r := strings.NewReader(`{"LEVEL": {"name": "warn", "priority": 2}}`)
+	if err := lookup.Lookup(&c, nil, lookup.NewJSONReader(r)); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if expected := (level{"warn", 2}); c.Level != expected {
+		t.Errorf("Unexpected result: got %#v, expecting %#v", c.Level, expected)
+	}
+
+	if err := lookup.Lookup(&c, nil, lookup.Map{"LEVEL": "x", "START": "yesterday"}); err == nil {
+		t.Error("Time is invalid, why no error?!")
+	}
+}
+
+type color string
+
+func (c *color) Set(s string) error {
+	switch s {
+	case "red", "green", "blue":
+		*c = color(strings.ToUpper(s))
+		return nil
+	}
+	return fmt.Errorf("unknown color %q", s)
+}
+
+func (c *color) String() string {
+	return string(*c)
+}
+
+func TestLookupFlagValue(t *testing.T) {
+	var c struct {
+		Color color `lookup:"COLOR"`
+	}
+	var e entries
+	if err := lookup.Lookup(&c, &e, lookup.Map{"COLOR": "red"}); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if expected := (entries{"COLOR", "RED"}); c.Color != "RED" || !reflect.DeepEqual(e, expected) {
+		t.Errorf("Unexpected result: %q/%#v", c.Color, e)
+	}
+
+	err := lookup.Lookup(&c, nil, lookup.Map{"COLOR": "pink"})
+	if err == nil || !strings.Contains(err.Error(), `"Color"`) || !strings.Contains(err.Error(), `unknown color "pink"`) {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
+
+func TestLookupDuration(t *testing.T) {
+	o := lookup.Options{DurationKeywords: map[string]time.Duration{
+		"unlimited": math.MaxInt64,
+		"none":      0,
+	}}
+	tests := []struct {
+		in       string
+		expected time.Duration
+	}{
+		{"1m30s", 90 * time.Second},
+		{"-1", -1},
+		{"-1s", -time.Second},
+		{"unlimited", math.MaxInt64},
+		{"none", 0},
+	}
+	for _, test := range tests {
+		t.Run(test.in, func(t *testing.T) {
+			var c struct {
+				Timeout time.Duration `lookup:"TIMEOUT"`
+				Nanos   int64         `lookup:"NANOS,unit=duration"`
+			}
+			if err := o.Lookup(&c, nil, lookup.Map{"TIMEOUT": test.in, "NANOS": test.in}); err != nil {
+				t.Fatalf("Unexpected error: %s", err)
+			}
+			if c.Timeout != test.expected || c.Nanos != int64(test.expected) {
+				t.Errorf("Unexpected result: got %v/%d, expecting %v", c.Timeout, c.Nanos, test.expected)
+			}
+		})
+	}
+
+	var c struct {
+		Timeout time.Duration `lookup:"TIMEOUT"`
+	}
+	err := o.Lookup(&c, nil, lookup.Map{"TIMEOUT": "forever"})
+	if err == nil || !strings.Contains(err.Error(), `expecting "none", "unlimited" or a valid duration`) {
+		t.Errorf("Unexpected error for unknown keyword: %v", err)
+	}
+}
+
+func TestLookupIndexed(t *testing.T) {
+	type conf struct {
+		Items []int      `lookup:"ITEM,indexed"`
+		Hosts []string   `lookup:"HOST,optional,indexed"`
+		Pair  [2]float64 `lookup:"PAIR,optional,indexed"`
+	}
+	var c conf
+	var e entries
+	m := lookup.Map{"ITEM_0": "1", "ITEM_1": "2", "ITEM_2": "3", "ITEM_4": "5", "HOST_1": "a,b"}
+	if err := lookup.Lookup(&c, &e, m); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if !reflect.DeepEqual(c.Items, []int{1, 2, 3}) || c.Hosts != nil {
+		t.Errorf("Unexpected result: %#v", c)
+	}
+	if expected := (entries{"ITEM", "[1 2 3]", "HOST", "", "PAIR", ""}); !reflect.DeepEqual(e, expected) {
+		t.Errorf("Unexpected reports: %#v, expecting %#v", e, expected)
+	}
+
+	o := lookup.Options{IndexSeparator: ".", IndexStart: 1}
+	m = lookup.Map{"ITEM.1": "7", "HOST.1": "a,b", "HOST.2": "c"}
+	if err := o.Lookup(&c, nil, m); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if !reflect.DeepEqual(c.Items, []int{7}) || !reflect.DeepEqual(c.Hosts, []string{"a,b", "c"}) {
+		t.Errorf("Unexpected result: %#v", c)
+	}
+
+	for _, m := range []lookup.Map{
+		{"ITEM_1": "1"},
+		{"ITEM_0": "x"},
+		{"ITEM_0": "1", "PAIR_0": "1.5"},
+	} {
+		if err := lookup.Lookup(&c, nil, m); err == nil {
+			t.Errorf("Invalid values %v, why no error?!", m)
+		}
+	}
+}
+
+type (
+	levelName string
+	blob      []byte
+)
+
+func TestLookupNamedTypes(t *testing.T) {
+	var c struct {
+		Level levelName `lookup:"LEVEL"`
+		Data  blob      `lookup:"DATA"`
+		Raw   blob      `lookup:"RAW,raw"`
+	}
+	var e entries
+	if err := lookup.Lookup(&c, &e, lookup.Map{"LEVEL": "very verbose", "DATA": "c2VjcmV0", "RAW": "a,b"}); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if c.Level != "very verbose" || string(c.Data) != "secret" || string(c.Raw) != "a,b" {
+		t.Errorf("Unexpected result: %#v", c)
+	}
+	if expected := (entries{"LEVEL", "very verbose", "DATA", "[115 101 99 114 101 116]", "RAW", "[97 44 98]"}); !reflect.DeepEqual(e, expected) {
+		t.Errorf("Unexpected reports: %#v, expecting %#v", e, expected)
+	}
+}
+
+func TestLookupTagDefaults(t *testing.T) {
+	type conf struct {
+		Hosts []string  `lookup:"HOSTS"`
+		Ports []int     `lookup:"PORTS,sep=|"`
+		Key   []byte    `lookup:"KEY"`
+		Token []byte    `lookup:"TOKEN,enc=base64"`
+		Start time.Time `lookup:"START"`
+		Day   time.Time `lookup:"DAY,layout=2006-01-02"`
+	}
+	m := lookup.Map{
+		"HOSTS": "a;b",
+		"PORTS": "80|443",
+		"KEY":   "beef",
+		"TOKEN": "c2VjcmV0",
+		"START": "Tue, 02 Jun 2020 10:00:00 UTC",
+		"DAY":   "2020-06-03",
+	}
+	var c conf
+	if err := lookup.Lookup(&c, nil, m); err == nil {
+		t.Error("START is not RFC 3339, why no error?!")
+	}
+
+	o := lookup.Options{ListSeparator: ";", ByteEncoding: "hex", TimeLayout: time.RFC1123}
+	if err := o.Lookup(&c, nil, m); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	start := time.Date(2020, 6, 2, 10, 0, 0, 0, time.UTC)
+	day := time.Date(2020, 6, 3, 0, 0, 0, 0, time.UTC)
+	switch {
+	case !reflect.DeepEqual(c.Hosts, []string{"a", "b"}), !reflect.DeepEqual(c.Ports, []int{80, 443}):
+		t.Errorf("Unexpected lists: %q/%v", c.Hosts, c.Ports)
+	case string(c.Key) != "\xbe\xef", string(c.Token) != "secret":
+		t.Errorf("Unexpected bytes: %q/%q", c.Key, c.Token)
+	case !c.Start.Equal(start), !c.Day.Equal(day):
+		t.Errorf("Unexpected times: %s/%s", c.Start, c.Day)
+	}
+}
+
+func TestLookupItemOptions(t *testing.T) {
+	type conf struct {
+		Days   []time.Time `lookup:"DAYS"`
+		Times  []time.Time `lookup:"TIMES,layout=15:04"`
+		Keys   [][]byte    `lookup:"KEYS,enc=hex"`
+		Sizes  []int64     `lookup:"SIZES,unit=bytes"`
+		Limits [2]int      `lookup:"LIMIT,indexed,unit=bytes"`
+	}
+	m := lookup.Map{
+		"DAYS":    "2020-06-02,2020-06-03",
+		"TIMES":   "10:30,18:00",
+		"KEYS":    "beef,cafe",
+		"SIZES":   "1KiB,2MB",
+		"LIMIT_0": "1KB",
+		"LIMIT_1": "1KiB",
+	}
+	var c conf
+	o := lookup.Options{TimeLayout: "2006-01-02"}
+	if err := o.Lookup(&c, nil, m); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	days := []time.Time{time.Date(2020, 6, 2, 0, 0, 0, 0, time.UTC), time.Date(2020, 6, 3, 0, 0, 0, 0, time.UTC)}
+	times := []time.Time{time.Date(0, 1, 1, 10, 30, 0, 0, time.UTC), time.Date(0, 1, 1, 18, 0, 0, 0, time.UTC)}
+	switch {
+	case !reflect.DeepEqual(c.Days, days), !reflect.DeepEqual(c.Times, times):
+		t.Errorf("Unexpected times: %s/%s", c.Days, c.Times)
+	case !reflect.DeepEqual(c.Keys, [][]byte{{0xbe, 0xef}, {0xca, 0xfe}}):
+		t.Errorf("Unexpected keys: %q", c.Keys)
+	case !reflect.DeepEqual(c.Sizes, []int64{1024, 2000000}), c.Limits != [2]int{1000, 1024}:
+		t.Errorf("Unexpected sizes: %v/%v", c.Sizes, c.Limits)
+	}
+
+	d, err := o.Dump(c)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if d["DAYS"] != m["DAYS"] || d["TIMES"] != m["TIMES"] || d["KEYS"] != m["KEYS"] {
+		t.Errorf("Unexpected dump: %q", d)
+	}
+	var out conf
+	if err := o.Lookup(&out, nil, d); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if !reflect.DeepEqual(out, c) {
+		t.Errorf("Unexpected result: got %+v, expecting %+v", out, c)
+	}
+}
@@ -0,0 +1,40 @@
+package lookup
+
+import "time"
+
+type timedLooker struct {
+	Looker
+	name string
+	sink func(name string, d time.Duration)
+}
+
+// NewTimed returns a Looker that passes the duration of each LookupKey call in l to sink, with
+// name, to find slow sources. With a nil sink, l is returned unchanged.
+func NewTimed(name string, l Looker, sink func(name string, d time.Duration)) Looker {
+	if sink == nil {
+		return l
+	}
+	return &timedLooker{
+		Looker: l,
+		name:   name,
+		sink:   sink,
+	}
+}
+
+func (l *timedLooker) LookupKey(k string) (string, bool, error) {
+	start := time.Now()
+	v, ok, err := l.Looker.LookupKey(k)
+	l.sink(l.name, time.Since(start))
+	return v, ok, err
+}
+
+// Keys returns the keys of the wrapped Looker, or nil if it is not Enumerable.
+func (l *timedLooker) Keys() []string {
+	keys, _ := keysOf(l.Looker)
+	return keys
+}
+
+// KeysErr is like Keys, but also returns the error loading the keys of the wrapped Looker.
+func (l *timedLooker) KeysErr() ([]string, error) {
+	return keysOf(l.Looker)
+}
@@ -0,0 +1,40 @@
+package lookup_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/carloslenz/lookup"
+)
+
+func TestTimed(t *testing.T) {
+	slow := lookup.LookerFunc(func(k string) (string, bool, error) {
+		time.Sleep(time.Millisecond)
+		return "", false, nil
+	})
+	totals := make(map[string]time.Duration)
+	calls := make(map[string]int)
+	sink := func(name string, d time.Duration) {
+		totals[name] += d
+		calls[name]++
+	}
+	var c struct {
+		A string `lookup:"A"`
+		B string `lookup:"B"`
+	}
+	seq := []lookup.Looker{lookup.NewTimed("remote", slow, sink), lookup.NewTimed("defaults", lookup.Map{"A": "1", "B": "2"}, sink)}
+	if err := lookup.Lookup(&c, nil, seq...); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if calls["remote"] != 2 || calls["defaults"] != 2 || totals["remote"] < 2*time.Millisecond {
+		t.Errorf("Unexpected timings: %v, %v", calls, totals)
+	}
+
+	m := lookup.Map{"A": "1"}
+	if l := lookup.NewTimed("map", m, nil); l.(lookup.Map)["A"] != "1" {
+		t.Errorf("Nil sink should return the looker unchanged: %#v", l)
+	}
+	if keys := lookup.ListAll(seq...); len(keys) != 2 {
+		t.Errorf("Unexpected keys: %v", keys)
+	}
+}